@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+)
+
+const (
+	branchesDirname = "branches"
+)
+
+// BranchPoint records a timeline that a branch was forked from. Branch is empty for the
+// original timeline.
+type BranchPoint struct {
+	Branch   string
+	StateDir string
+	Step     int
+}
+
+func (s BranchPoint) String() string {
+	timeline := s.Branch
+	if len(timeline) == 0 {
+		timeline = "canon"
+	}
+
+	return fmt.Sprintf("%s (%s) at step %d", timeline, s.StateDir, s.Step)
+}
+
+// BranchStateDir is where a branch of the simulation with the given name keeps its state.
+func (s *Simulation) BranchStateDir(name string) string {
+	return path.Join(s.StateDir, branchesDirname, DocumentID(name))
+}
+
+func copyFile(from, to string) error {
+	source, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+
+	defer source.Close()
+
+	if destination, err := os.OpenFile(to, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0644); err != nil {
+		return err
+	} else if _, err := io.Copy(destination, source); err != nil {
+		destination.Close()
+		return err
+	} else {
+		return destination.Close()
+	}
+}
+
+// copyIfPresent copies a file that may not exist, such as the report of a turn that was run
+// before reports were kept.
+func copyIfPresent(from, to string) error {
+	if _, err := os.Stat(from); os.IsNotExist(err) {
+		return nil
+	}
+
+	return copyFile(from, to)
+}
+
+// Fork forks the simulation at an earlier step into a new timeline with its own state
+// directory. The history up to that step is copied over, along with any orders already given
+// for the step after it, so that the branch plays out the same as this timeline until the GM
+// changes something.
+func (s *Simulation) Fork(name string, step int) (*Simulation, error) {
+	if step < 0 || step > s.Step {
+		return nil, fmt.Errorf("step %d is outside of the simulation's history (0 - %d)", step, s.Step)
+	}
+
+	branch := &Simulation{
+		Name:     s.Name,
+		Step:     step,
+		Seed:     s.Seed,
+		Branch:   name,
+		Lineage:  append(append([]BranchPoint(nil), s.Lineage...), BranchPoint{Branch: s.Branch, StateDir: s.StateDir, Step: step}),
+		StateDir: s.BranchStateDir(name),
+	}
+
+	if len(s.TurnSeeds) > step {
+		branch.TurnSeeds = append([]int64(nil), s.TurnSeeds[:step]...)
+	} else {
+		branch.TurnSeeds = append([]int64(nil), s.TurnSeeds...)
+	}
+
+	if _, err := os.Stat(branch.StatePath()); err == nil {
+		return nil, fmt.Errorf("a branch named %s already exists in %s", name, branch.StateDir)
+	}
+
+	world, err := LoadWorld(s.WorldPathForStep(step))
+	if err != nil {
+		return nil, err
+	}
+
+	branch.World = world
+
+	if err := os.MkdirAll(path.Join(branch.StateDir, ordersDirname), 0755); err != nil {
+		return nil, err
+	}
+
+	for past := 0; past <= step; past++ {
+		if err := copyFile(s.WorldPathForStep(past), branch.WorldPathForStep(past)); err != nil {
+			return nil, err
+		} else if past == 0 {
+			continue
+		}
+
+		original := &Simulation{StateDir: s.StateDir, Step: past}
+		forked := &Simulation{StateDir: branch.StateDir, Step: past}
+
+		if err := copyIfPresent(original.RenderedPath(), forked.RenderedPath()); err != nil {
+			return nil, err
+		} else if err := copyIfPresent(original.RollLogPath(), forked.RollLogPath()); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, actor := range world.SortedActors() {
+		for past := 1; past <= step+1; past++ {
+			if err := copyIfPresent(OrdersPath(s.StateDir, actor.Name, past), OrdersPath(branch.StateDir, actor.Name, past)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := branch.Write(); err != nil {
+		return nil, err
+	}
+
+	return branch, nil
+}
+
+func runBranch(args []string) error {
+	flags, stateDir := newFlagSet("branch")
+	from := flags.String("from", "", "step to branch from, defaults to the current step")
+	seed := flags.String("seed", "", "new seed for the branch's future turns, defaults to the simulation seed")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	} else if len(positional) != 1 {
+		return fmt.Errorf("branch expects the name of the new branch")
+	}
+
+	simulation, err := LoadSimulation(*stateDir)
+	if err != nil {
+		return err
+	}
+
+	step := simulation.Step
+	if len(*from) > 0 {
+		if step, err = strconv.Atoi(*from); err != nil {
+			return fmt.Errorf("%s is not a valid step: %v", *from, err)
+		}
+	}
+
+	branch, err := simulation.Fork(positional[0], step)
+	if err != nil {
+		return err
+	}
+
+	if len(*seed) > 0 {
+		if branch.Seed, err = strconv.ParseInt(*seed, 10, 64); err != nil {
+			return fmt.Errorf("%s is not a valid seed: %v", *seed, err)
+		} else if err := branch.Write(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Branched %s from step %d into %s\n", branch.Branch, step, branch.StateDir)
+	return nil
+}
@@ -0,0 +1,615 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultStateDir = "state"
+)
+
+// Command is a single warsim subcommand. Each command parses its own flags from the arguments
+// that follow the command name.
+type Command struct {
+	Name        string
+	Usage       string
+	Description string
+	Run         func(args []string) error
+}
+
+var commands []*Command
+
+func init() {
+	commands = []*Command{
+		{
+			Name:        "init",
+			Usage:       "init [--state-dir DIR] [--scenario-dir DIR] [--name NAME] [--seed SEED] <scenario> | --world FILE | --generate [--actors N] [--size N] [--fort-density D] [--army-budget B]",
+			Description: "Start a new simulation at step 0 from a scenario, a ready-made world file or a generated world.",
+			Run:         runInit,
+		},
+		{
+			Name:        "turn",
+			Usage:       "turn [--state-dir DIR] [--seed SEED]",
+			Description: "Run a single turn of the simulation, optionally with an explicit seed.",
+			Run:         runTurn,
+		},
+		{
+			Name:        "run",
+			Usage:       "run [--state-dir DIR] -n N",
+			Description: "Run N turns of the simulation.",
+			Run:         runRun,
+		},
+		{
+			Name:        "status",
+			Usage:       "status [--state-dir DIR]",
+			Description: "Summarize the current state of the simulation.",
+			Run:         runStatus,
+		},
+		{
+			Name:        "show",
+			Usage:       "show [--state-dir DIR] <army|settlement> <name>",
+			Description: "Show the details of a single army or settlement.",
+			Run:         runShow,
+		},
+		{
+			Name:        "validate",
+			Usage:       "validate [--state-dir DIR] [--all] [world file...]",
+			Description: "Check the simulation's worlds and orders, or the given world files, for broken references.",
+			Run:         runValidate,
+		},
+		{
+			Name:        "odds",
+			Usage:       "odds <roll> [--vs-ac AC] [--table]",
+			Description: "Show the exact distribution of a roll and its chance to hit an AC.",
+			Run:         runOdds,
+		},
+		{
+			Name:        "console",
+			Usage:       "console [--state-dir DIR] [--replay JOURNAL]",
+			Description: "Start an interactive GM console, optionally replaying a journal first.",
+			Run:         runConsole,
+		},
+		{
+			Name:        "rewind",
+			Usage:       "rewind [--state-dir DIR] [--force] <step>",
+			Description: "Set the simulation back to an earlier step, discarding the steps after it.",
+			Run:         runRewind,
+		},
+		{
+			Name:        "branch",
+			Usage:       "branch [--state-dir DIR] [--from STEP] [--seed SEED] <name>",
+			Description: "Fork a what-if timeline from a step into its own state directory.",
+			Run:         runBranch,
+		},
+		{
+			Name:        "diff",
+			Usage:       "diff [--state-dir DIR] [--format text|json|html] <stepA> <stepB>",
+			Description: "List what changed in the world between two steps.",
+			Run:         runDiff,
+		},
+		{
+			Name:        "montecarlo",
+			Usage:       "montecarlo [--state-dir DIR] [--runs N] [--turns K] [--seed SEED] [--workers W] [--csv FILE]",
+			Description: "Run the campaign forward many times from the current step and report how it tends to end.",
+			Run:         runMonteCarlo,
+		},
+	}
+}
+
+func findCommand(name string) (*Command, bool) {
+	for _, command := range commands {
+		if command.Name == name {
+			return command, true
+		}
+	}
+
+	return nil, false
+}
+
+func printUsage() {
+	fmt.Printf("Usage: warsim <command> [arguments]\n\nCommands:\n")
+
+	for _, command := range commands {
+		fmt.Printf("  %s\n      %s\n", command.Usage, command.Description)
+	}
+}
+
+// newFlagSet creates the flag set for a command along with the --state-dir flag that every
+// command shares.
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	stateDir := flags.String("state-dir", defaultStateDir, "directory holding the simulation state")
+
+	return flags, stateDir
+}
+
+// parseInterspersed parses flags that may appear before, between or after positional
+// arguments and returns the positional arguments.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		} else if flags.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+func runInit(args []string) error {
+	flags, stateDir := newFlagSet("init")
+	scenarioDir := flags.String("scenario-dir", defaultScenarioDir, "directory holding the built-in scenarios")
+	worldPath := flags.String("world", "", "start from a ready-made world file instead of a scenario")
+	name := flags.String("name", "", "name of the simulation, defaults to the scenario's name")
+	seed := flags.Int64("seed", time.Now().UnixNano(), "seed for every roll the simulation makes, and for generating the world")
+	generate := flags.Bool("generate", false, "generate a random world instead of using a scenario")
+	actors := flags.Int("actors", 2, "number of actors in a generated world")
+	size := flags.Int("size", 12, "number of settlements in a generated world")
+	fortDensity := flags.Float64("fort-density", 0.5, "chance from 0 to 1 of a generated settlement having each extra fortification")
+	armyBudget := flags.Int("army-budget", 500, "what each actor in a generated world may spend on armies")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	} else if sources := len(positional); (len(*worldPath) > 0 && sources != 0) || (*generate && sources != 0) || (*generate && len(*worldPath) > 0) {
+		return fmt.Errorf("init expects only one of a scenario, a world file or --generate")
+	} else if len(*worldPath) == 0 && !*generate && sources != 1 {
+		return fmt.Errorf("init expects a scenario name or scenario file")
+	}
+
+	if _, err := os.Stat(path.Join(*stateDir, stateFilename)); err == nil {
+		return fmt.Errorf("a simulation already exists in %s", *stateDir)
+	}
+
+	var world *World
+
+	if len(*worldPath) > 0 {
+		if world, err = LoadWorld(*worldPath); err != nil {
+			return err
+		} else if len(*name) == 0 {
+			// Use the world file name without any extensions
+			*name = strings.SplitN(filepath.Base(*worldPath), ".", 2)[0]
+		}
+	} else if *generate {
+		options := GeneratorOptions{
+			Seed:                 *seed,
+			Actors:               *actors,
+			Settlements:          *size,
+			FortificationDensity: *fortDensity,
+			ArmyBudget:           *armyBudget,
+		}
+
+		if scenario, err := GenerateScenario(options); err != nil {
+			return err
+		} else if world, err = scenario.Build(); err != nil {
+			return err
+		} else if problems := world.Validate(); len(problems) > 0 {
+			return &ValidationError{Path: "generated world", Problems: problems}
+		} else if len(*name) == 0 {
+			*name = scenario.Name
+		}
+	} else if scenarioPath, err := FindScenario(*scenarioDir, positional[0]); err != nil {
+		return err
+	} else if scenario, err := LoadScenario(scenarioPath); err != nil {
+		return err
+	} else if world, err = scenario.Build(); err != nil {
+		return fmt.Errorf("%s: %v", scenarioPath, err)
+	} else if problems := world.Validate(); len(problems) > 0 {
+		return &ValidationError{Path: scenarioPath, Problems: problems}
+	} else if len(*name) == 0 {
+		*name = scenario.Name
+	}
+
+	simulation := &Simulation{
+		Name:     *name,
+		Step:     0,
+		Seed:     *seed,
+		StateDir: *stateDir,
+		World:    world,
+	}
+
+	if err := os.MkdirAll(*stateDir, 0755); err != nil {
+		return err
+	} else if err := WriteWorld(simulation.WorldPath(), world); err != nil {
+		return err
+	} else if err := simulation.Write(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Created simulation %s in %s with seed %d\n", simulation.Name, *stateDir, simulation.Seed)
+	return nil
+}
+
+func runTurn(args []string) error {
+	flags, stateDir := newFlagSet("turn")
+	seed := flags.String("seed", "", "seed for this turn's rolls, defaults to one derived from the simulation seed")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	simulation, err := LoadSimulation(*stateDir)
+	if err != nil {
+		return err
+	} else if len(*seed) == 0 {
+		return simulation.Turn()
+	}
+
+	if turnSeed, err := strconv.ParseInt(*seed, 10, 64); err != nil {
+		return fmt.Errorf("%s is not a valid seed: %v", *seed, err)
+	} else {
+		return simulation.TurnWithSeed(turnSeed)
+	}
+}
+
+func runRun(args []string) error {
+	flags, stateDir := newFlagSet("run")
+	turns := flags.Int("n", 1, "number of turns to run")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	} else if *turns < 1 {
+		return fmt.Errorf("the number of turns must be at least 1")
+	}
+
+	simulation, err := LoadSimulation(*stateDir)
+	if err != nil {
+		return err
+	}
+
+	for turn := 0; turn < *turns; turn++ {
+		if err := simulation.Turn(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func runStatus(args []string) error {
+	flags, stateDir := newFlagSet("status")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	simulation, err := LoadSimulation(*stateDir)
+	if err != nil {
+		return err
+	}
+
+	printStatus(simulation)
+	return nil
+}
+
+func printStatus(simulation *Simulation) {
+	world := simulation.World
+	settlementsByActor := world.SettlementsByActor()
+	armiesByActor := world.ArmiesByActor()
+
+	Printf("%s, step %d", simulation.Name, simulation.Step)
+	fmt.Printf("  Seed: %d\n", simulation.Seed)
+
+	if len(simulation.Branch) > 0 {
+		Printf("  Branch: %s", simulation.Branch)
+	}
+
+	for _, point := range simulation.Lineage {
+		Printf("  Branched from %s", point)
+	}
+
+	if simulation.Step > 0 && len(simulation.TurnSeeds) >= simulation.Step {
+		fmt.Printf("  Last turn seed: %d\n", simulation.TurnSeeds[simulation.Step-1])
+	}
+
+	for _, actor := range world.SortedActors() {
+		var (
+			activeArmies = 0
+			armyHP       = 0
+		)
+
+		for _, army := range armiesByActor[actor.Name] {
+			if !army.Destroyed {
+				activeArmies++
+				armyHP += army.HP.Current
+			}
+		}
+
+		Printf("  %s: %d settlements, %d of %d armies active (%d HP), holding %s",
+			actor.Name, len(settlementsByActor[actor.Name]), activeArmies, len(armiesByActor[actor.Name]), armyHP, actor.Holdings())
+	}
+}
+
+func runShow(args []string) error {
+	flags, stateDir := newFlagSet("show")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	} else if flags.NArg() != 2 {
+		return fmt.Errorf("show expects a kind (army or settlement) and a name")
+	}
+
+	simulation, err := LoadSimulation(*stateDir)
+	if err != nil {
+		return err
+	}
+
+	return showEntity(simulation.World, flags.Arg(0), flags.Arg(1))
+}
+
+func showEntity(world *World, kind, name string) error {
+	switch kind {
+	case "army":
+		if army, found := world.Armies[name]; !found {
+			return fmt.Errorf("there is no army named %s", name)
+		} else {
+			printArmy(army)
+		}
+
+	case "settlement":
+		if settlement, found := world.Settlements[name]; !found {
+			return fmt.Errorf("there is no settlement named %s", name)
+		} else {
+			printSettlement(settlement, world.ArmiesAt(name))
+		}
+
+	default:
+		return fmt.Errorf("unknown kind %s, expected army or settlement", kind)
+	}
+
+	return nil
+}
+
+func printArmy(army *Army) {
+	Printf("%s", army.Name)
+	Printf("  Allegiance:    %s", army.Allegiance)
+	Printf("  Location:      %s", army.LocationDescription())
+	Printf("  Destination:   %s", army.Destination)
+	Printf("  Route:         %s", army.RemainingRoute())
+	Printf("  HP:            %d / %d", army.HP.Current, army.HP.Max)
+	Printf("  AC:            %d", army.AC)
+	Printf("  Attack Roll:   %s", army.AttackRoll)
+	Printf("  Attack Damage: %s", army.DamageRoll)
+	Printf("  Morale:        %d / %d", army.Morale, maxMorale)
+
+	if army.Routed {
+		Printf("  Routed")
+	}
+
+	if army.SiegeEngines {
+		Printf("  Siege Engines: %t", army.SiegeEngines)
+	}
+
+	if army.Surgeons {
+		Printf("  Surgeons:      %t", army.Surgeons)
+	}
+
+	if len(army.Target) > 0 {
+		Printf("  Target:        %s", army.Target)
+	}
+
+	if len(army.Stance) > 0 {
+		Printf("  Stance:        %s", army.Stance)
+	}
+
+	if army.Destroyed {
+		Printf("  Destroyed")
+	}
+}
+
+func printSettlement(settlement *Settlement, armies ArmyList) {
+	Printf("%s", settlement.Name)
+	Printf("  Allegiance:    %s", settlement.Allegiance)
+	Printf("  Occupied:      %t", settlement.Occupied)
+	Printf("  Population:    %d", settlement.Population)
+	Printf("  HP:            %d / %d", settlement.HP.Current, settlement.HP.Max)
+	Printf("  AC:            %d (%s)", settlement.AC(), settlement.ACBreakdown())
+	Printf("  Attack Roll:   %s", settlement.AttackRoll())
+	Printf("  Attack Damage: %s", settlement.DamageRoll)
+	Printf("  War Guard:     %t", settlement.HasWarGuard)
+
+	for _, effect := range settlement.FortificationEffects() {
+		fortification := effect.Fortification
+		Printf("  Fortification: %s (%s, defense %+d, attack %+d)",
+			fortification.Name, fortification.Type, fortification.DefenseModifier, fortification.AttackModifier)
+
+		if fortification.HP != nil {
+			Printf("                 HP: %d / %d", fortification.HP.Current, fortification.HP.Max)
+		}
+
+		if !effect.Counted {
+			Printf("                 Doesn't count: %s", effect.Reason)
+		}
+	}
+
+	if settlement.Repairing {
+		Printf("  Repairing fortifications")
+	}
+
+	for _, project := range settlement.Construction {
+		Printf("  Under Construction: %s (%d turns left)", project.Blueprint, project.TurnsLeft)
+	}
+
+	for _, recruit := range settlement.Training {
+		Printf("  In Training:   %s (%s, %d turns left)", recruit.Name, recruit.Archetype, recruit.TurnsLeft)
+	}
+
+	for _, army := range armies.Sorted() {
+		if !army.Destroyed {
+			Printf("  Army Present:  %s (%s)", army.Name, army.Allegiance)
+		}
+	}
+}
+
+func runValidate(args []string) error {
+	flags, stateDir := newFlagSet("validate")
+	all := flags.Bool("all", false, "check the world file of every step, not just the current one")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	}
+
+	// World files named on the command line are checked on their own, such as a scenario
+	// before a simulation is started from it
+	if len(positional) > 0 {
+		return validateWorldFiles(positional)
+	}
+
+	simulation, err := LoadSimulationState(*stateDir)
+	if err != nil {
+		return err
+	}
+
+	var worldPaths []string
+	if *all {
+		for step := 0; step <= simulation.Step; step++ {
+			worldPaths = append(worldPaths, simulation.WorldPathForStep(step))
+		}
+	} else {
+		worldPaths = append(worldPaths, simulation.WorldPath())
+	}
+
+	if err := validateWorldFiles(worldPaths); err != nil {
+		return err
+	} else if simulation, err = LoadSimulation(*stateDir); err != nil {
+		return err
+	} else if _, err := LoadOrders(simulation.StateDir, simulation.World, simulation.Step+1); err != nil {
+		return err
+	}
+
+	fmt.Printf("Simulation %s at step %d is valid\n", simulation.Name, simulation.Step)
+	return nil
+}
+
+// validateWorldFiles loads each world file and prints every problem found rather than stopping
+// at the first bad file.
+func validateWorldFiles(worldPaths []string) error {
+	invalid := 0
+
+	for _, worldPath := range worldPaths {
+		if _, err := LoadWorld(worldPath); err != nil {
+			invalid++
+
+			if validationErr, isValidation := err.(*ValidationError); isValidation {
+				for _, problem := range validationErr.Problems {
+					fmt.Printf("%s: %s\n", worldPath, problem)
+				}
+			} else {
+				fmt.Printf("%s: %v\n", worldPath, err)
+			}
+		} else {
+			fmt.Printf("%s: ok\n", worldPath)
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d world files are invalid", invalid, len(worldPaths))
+	}
+
+	return nil
+}
+
+func runRewind(args []string) error {
+	flags, stateDir := newFlagSet("rewind")
+	force := flags.Bool("force", false, "discard the steps after the one rewound to")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	} else if len(positional) != 1 {
+		return fmt.Errorf("rewind expects the step to rewind to")
+	}
+
+	step, err := strconv.Atoi(positional[0])
+	if err != nil {
+		return fmt.Errorf("%s is not a valid step: %v", positional[0], err)
+	}
+
+	simulation, err := LoadSimulation(*stateDir)
+	if err != nil {
+		return err
+	} else if step < 0 || step > simulation.Step {
+		return fmt.Errorf("step %d is outside of the simulation's history (0 - %d)", step, simulation.Step)
+	} else if step < simulation.Step && !*force {
+		return fmt.Errorf("rewinding to step %d would discard steps %d to %d, branch from step %d first to keep them or pass --force",
+			step, step+1, simulation.Step, simulation.Step)
+	} else if _, err := LoadWorld(simulation.WorldPathForStep(step)); err != nil {
+		return err
+	}
+
+	if err := simulation.Discard(step); err != nil {
+		return err
+	}
+
+	fmt.Printf("Rewound simulation %s to step %d\n", simulation.Name, step)
+	return nil
+}
+
+func runOdds(args []string) error {
+	flags := flag.NewFlagSet("odds", flag.ContinueOnError)
+	vsAC := flags.Int("vs-ac", 0, "AC the roll must meet or beat")
+	showTable := flags.Bool("table", false, "print the chance of every total")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	} else if len(positional) != 1 {
+		return fmt.Errorf("odds expects a single roll expression")
+	}
+
+	// AC 0 is a fair target, so only a flag that was given asks for the chance to hit
+	acGiven := false
+	flags.Visit(func(given *flag.Flag) {
+		if given.Name == "vs-ac" {
+			acGiven = true
+		}
+	})
+
+	roll := RollSpec{Die(positional[0])}
+
+	distribution, err := roll.Distribution()
+	if err != nil {
+		return err
+	}
+
+	// Every figure worked out from sample rolls is marked as an estimate
+	estimate := ""
+
+	fmt.Printf("%s\n", roll)
+	if distribution.Samples > 0 {
+		fmt.Printf("  Estimated from %d rolls, too many dice to work out exactly\n", distribution.Samples)
+		estimate = " (estimated)"
+	}
+
+	fmt.Printf("  Min:      %d%s\n", distribution.Min(), estimate)
+	fmt.Printf("  Max:      %d%s\n", distribution.Max(), estimate)
+	fmt.Printf("  Mean:     %.3f%s\n", distribution.Mean(), estimate)
+	fmt.Printf("  Variance: %.3f%s\n", distribution.Variance(), estimate)
+	fmt.Printf("  Std Dev:  %.3f%s\n", math.Sqrt(distribution.Variance()), estimate)
+
+	if acGiven {
+		fmt.Printf("  Chance to meet or beat AC %d: %.2f%%%s\n", *vsAC, distribution.ChanceAtLeast(*vsAC)*100, estimate)
+	}
+
+	if *showTable {
+		for value := distribution.Min(); value <= distribution.Max(); value++ {
+			probability := distribution.Probability(value)
+			fmt.Printf("  %5d %7.3f%% %s\n", value, probability*100, strings.Repeat("#", int(probability*200+0.5)))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	journalsDirname = "journals"
+	journalHeader   = "# warsim journal, starting step "
+)
+
+// consoleCommand is a single command the GM may issue from the console. Commands that change
+// the world are recorded into the session journal so that they may be replayed later.
+type consoleCommand struct {
+	Usage    string
+	NumArgs  int
+	Journal  bool
+	Delegate func(console *Console, args []string) error
+}
+
+var consoleCommands map[string]consoleCommand
+
+func init() {
+	consoleCommands = map[string]consoleCommand{
+		"help": {
+			Usage:    "help",
+			Delegate: (*Console).help,
+		},
+		"status": {
+			Usage:    "status",
+			Delegate: (*Console).status,
+		},
+		"show": {
+			Usage:    "show <army|settlement> <name>",
+			NumArgs:  2,
+			Delegate: (*Console).show,
+		},
+		"damage": {
+			Usage:    "damage <name> <amount>",
+			NumArgs:  2,
+			Journal:  true,
+			Delegate: (*Console).damage,
+		},
+		"move": {
+			Usage:    "move <army> <settlement>",
+			NumArgs:  2,
+			Journal:  true,
+			Delegate: (*Console).move,
+		},
+		"set-allegiance": {
+			Usage:    "set-allegiance <name> <actor>",
+			NumArgs:  2,
+			Journal:  true,
+			Delegate: (*Console).setAllegiance,
+		},
+		"turn": {
+			Usage:    "turn",
+			Journal:  true,
+			Delegate: (*Console).turn,
+		},
+		"save": {
+			Usage:    "save",
+			Journal:  true,
+			Delegate: (*Console).save,
+		},
+	}
+}
+
+// splitCommandLine breaks a console line into arguments. Arguments containing spaces may be
+// wrapped in double quotes.
+func splitCommandLine(line string) ([]string, error) {
+	var (
+		args    []string
+		current strings.Builder
+		quoted  = false
+		inArg   = false
+	)
+
+	for _, char := range line {
+		switch {
+		case char == '"':
+			quoted = !quoted
+			inArg = true
+
+		case (char == ' ' || char == '\t' || char == '\n' || char == '\r') && !quoted:
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+
+		default:
+			current.WriteRune(char)
+			inArg = true
+		}
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in %q", strings.TrimSpace(line))
+	}
+
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args, nil
+}
+
+// quoteArg wraps an argument in quotes if it needs them to survive splitCommandLine.
+func quoteArg(arg string) string {
+	if len(arg) == 0 || strings.ContainsAny(arg, " \t") {
+		return fmt.Sprintf("\"%s\"", arg)
+	}
+
+	return arg
+}
+
+// Console is an interactive GM session against a loaded simulation.
+type Console struct {
+	Simulation *Simulation
+	Journal    *os.File
+}
+
+func (s *Console) Execute(line string) error {
+	args, err := splitCommandLine(line)
+	if err != nil {
+		return err
+	} else if len(args) == 0 {
+		return nil
+	}
+
+	command, found := consoleCommands[args[0]]
+	if !found {
+		return fmt.Errorf("unknown command %s, try help", args[0])
+	} else if len(args)-1 != command.NumArgs {
+		return fmt.Errorf("usage: %s", command.Usage)
+	} else if err := command.Delegate(s, args[1:]); err != nil {
+		return err
+	}
+
+	if command.Journal && s.Journal != nil {
+		quotedArgs := make([]string, len(args))
+		for idx, arg := range args {
+			quotedArgs[idx] = quoteArg(arg)
+		}
+
+		if _, err := fmt.Fprintln(s.Journal, strings.Join(quotedArgs, " ")); err != nil {
+			return fmt.Errorf("failed to record command in journal: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Console) help(args []string) error {
+	var names []string
+	for name := range consoleCommands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		Printf("  %s", consoleCommands[name].Usage)
+	}
+
+	Printf("  quit")
+	return nil
+}
+
+func (s *Console) status(args []string) error {
+	printStatus(s.Simulation)
+	return nil
+}
+
+func (s *Console) show(args []string) error {
+	return showEntity(s.Simulation.World, args[0], args[1])
+}
+
+func (s *Console) damage(args []string) error {
+	world := s.Simulation.World
+
+	amount, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("%s is not a valid amount: %v", args[1], err)
+	}
+
+	if army, found := world.Armies[args[0]]; found {
+		army.Damage(amount)
+		Printf("Army %s is at %d / %d HP", army.Name, army.HP.Current, army.HP.Max)
+	} else if settlement, found := world.Settlements[args[0]]; found {
+		settlement.HP.Damage(amount)
+		Printf("Settlement %s is at %d / %d HP", settlement.Name, settlement.HP.Current, settlement.HP.Max)
+	} else {
+		return fmt.Errorf("there is no army or settlement named %s", args[0])
+	}
+
+	return nil
+}
+
+func (s *Console) move(args []string) error {
+	world := s.Simulation.World
+
+	if army, found := world.Armies[args[0]]; !found {
+		return fmt.Errorf("there is no army named %s", args[0])
+	} else if _, found := world.Settlements[args[1]]; !found {
+		return fmt.Errorf("there is no settlement named %s", args[1])
+	} else {
+		// The GM places armies directly, taking them off the road if they were travelling
+		army.Location = args[1]
+		army.Destination = args[1]
+		army.Route = nil
+		army.Transit = nil
+
+		Printf("Army %s is now at %s", army.Name, army.Location)
+	}
+
+	return nil
+}
+
+func (s *Console) setAllegiance(args []string) error {
+	world := s.Simulation.World
+
+	if _, found := world.Actors[args[1]]; !found {
+		return fmt.Errorf("there is no actor named %s", args[1])
+	}
+
+	if army, found := world.Armies[args[0]]; found {
+		army.Allegiance = args[1]
+		Printf("Army %s now serves %s", army.Name, army.Allegiance)
+	} else if settlement, found := world.Settlements[args[0]]; found {
+		settlement.Allegiance = args[1]
+		Printf("Settlement %s now serves %s", settlement.Name, settlement.Allegiance)
+	} else {
+		return fmt.Errorf("there is no army or settlement named %s", args[0])
+	}
+
+	return nil
+}
+
+func (s *Console) turn(args []string) error {
+	if err := s.Simulation.Turn(); err != nil {
+		return err
+	}
+
+	Printf("Simulation is now at step %d", s.Simulation.Step)
+	return nil
+}
+
+func (s *Console) save(args []string) error {
+	if err := WriteWorld(s.Simulation.WorldPath(), s.Simulation.World); err != nil {
+		return err
+	} else if err := s.Simulation.Write(); err != nil {
+		return err
+	}
+
+	Printf("Saved %s", s.Simulation.WorldPath())
+	return nil
+}
+
+// Replay runs every command recorded in a journal. The simulation must be at the step the
+// journal was started from.
+func (s *Console) Replay(journalPath string) error {
+	file, err := os.Open(journalPath)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, journalHeader) {
+			if step, err := strconv.Atoi(strings.TrimPrefix(line, journalHeader)); err != nil {
+				return fmt.Errorf("%s:%d: bad journal header: %v", journalPath, lineNumber, err)
+			} else if step != s.Simulation.Step {
+				return fmt.Errorf("journal %s starts at step %d but the simulation is at step %d", journalPath, step, s.Simulation.Step)
+			}
+
+			continue
+		} else if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		Printf("> %s", line)
+		if err := s.Execute(line); err != nil {
+			return fmt.Errorf("%s:%d: %v", journalPath, lineNumber, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (s *Console) openJournal() error {
+	journalDir := path.Join(s.Simulation.StateDir, journalsDirname)
+	if err := os.MkdirAll(journalDir, 0755); err != nil {
+		return err
+	}
+
+	journalPath := path.Join(journalDir, fmt.Sprintf("%d.%d.journal", s.Simulation.Step, time.Now().UnixNano()))
+	if file, err := os.OpenFile(journalPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644); err != nil {
+		return err
+	} else if _, err := fmt.Fprintf(file, "%s%d\n", journalHeader, s.Simulation.Step); err != nil {
+		return err
+	} else {
+		s.Journal = file
+	}
+
+	fmt.Printf("Recording session to %s\n", journalPath)
+	return nil
+}
+
+func runConsole(args []string) error {
+	flags, stateDir := newFlagSet("console")
+	replayPath := flags.String("replay", "", "journal to replay before accepting commands")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	simulation, err := LoadSimulation(*stateDir)
+	if err != nil {
+		return err
+	}
+
+	console := &Console{
+		Simulation: simulation,
+	}
+
+	// Replayed commands are recorded into the new journal so that it stands alone
+	if err := console.openJournal(); err != nil {
+		return err
+	}
+
+	defer console.Journal.Close()
+
+	if len(*replayPath) > 0 {
+		if err := console.Replay(*replayPath); err != nil {
+			return err
+		}
+	}
+
+	stdinC := make(chan string)
+	launchStdinReader(stdinC)
+
+	for {
+		fmt.Printf("warsim:%d> ", simulation.Step)
+
+		line := strings.TrimSpace(<-stdinC)
+		if line == "quit" || line == "exit" {
+			return nil
+		} else if err := console.Execute(line); err != nil {
+			fmt.Printf("Error: %v.\n", err)
+		}
+	}
+}
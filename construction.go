@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Blueprint is a fortification that actors may build at their settlements. Building it costs
+// Cost from the actor's treasury and Materials from its stockpile, and takes Turns turns of
+// work. A blueprint that upgrades another fortification replaces it once built, and can only be
+// built where that fortification already stands.
+type Blueprint struct {
+	Type            FortificationType
+	DefenseModifier int
+	AttackModifier  int
+	Cost            int
+	Materials       int
+	Turns           int
+	Upgrades        string `toml:",omitempty"`
+}
+
+// ConstructionProject is a fortification being built at a settlement.
+type ConstructionProject struct {
+	Blueprint string
+	TurnsLeft int
+}
+
+// The blueprints used by worlds whose rules don't list their own
+var defaultBlueprints = map[string]Blueprint{
+	"Wooden Walls": {
+		Type:            Wall,
+		DefenseModifier: 10,
+		AttackModifier:  1,
+		Cost:            100,
+		Materials:       30,
+		Turns:           3,
+	},
+	"Stone Walls": {
+		Type:            Wall,
+		DefenseModifier: 15,
+		AttackModifier:  3,
+		Cost:            300,
+		Materials:       120,
+		Turns:           6,
+		Upgrades:        "Wooden Walls",
+	},
+	"Wooden Battlements": {
+		Type:            WallAddon,
+		DefenseModifier: 4,
+		AttackModifier:  1,
+		Cost:            80,
+		Materials:       20,
+		Turns:           2,
+	},
+	"Stone Battlements": {
+		Type:            WallAddon,
+		DefenseModifier: 7,
+		AttackModifier:  2,
+		Cost:            200,
+		Materials:       80,
+		Turns:           4,
+		Upgrades:        "Wooden Battlements",
+	},
+	"Barracks": {
+		Type:            Garrison,
+		DefenseModifier: 2,
+		AttackModifier:  2,
+		Cost:            150,
+		Materials:       40,
+		Turns:           3,
+	},
+	"Wooden Outer Walls": {
+		Type:            OuterWall,
+		DefenseModifier: 10,
+		AttackModifier:  1,
+		Cost:            250,
+		Materials:       100,
+		Turns:           5,
+	},
+}
+
+// Blueprints returns the fortifications that may be built in this world.
+func (s *World) Blueprints() map[string]Blueprint {
+	if s.Rules == nil || s.Rules.Blueprints == nil {
+		return defaultBlueprints
+	}
+
+	return s.Rules.Blueprints
+}
+
+// Price returns what it costs to build the blueprint.
+func (s Blueprint) Price() Resources {
+	return Resources{
+		Gold:      s.Cost,
+		Materials: s.Materials,
+	}
+}
+
+// Fortification returns the fortification the blueprint builds, at full HP.
+func (s Blueprint) Fortification(name string) Fortification {
+	maxHP := DefaultFortificationHP(s.DefenseModifier)
+
+	return Fortification{
+		Name:            name,
+		Type:            s.Type,
+		DefenseModifier: s.DefenseModifier,
+		AttackModifier:  s.AttackModifier,
+		HP: &HealthTracker{
+			Current: maxHP,
+			Max:     maxHP,
+		},
+	}
+}
+
+// FortificationNamed returns the settlement's fortification with the given name, or nil if it
+// has none.
+func (s *Settlement) FortificationNamed(name string) *Fortification {
+	for idx := range s.Fortifications {
+		if s.Fortifications[idx].Name == name {
+			return &s.Fortifications[idx]
+		}
+	}
+
+	return nil
+}
+
+// Building returns true if the settlement already has the blueprint queued for construction.
+func (s *Settlement) Building(blueprint string) bool {
+	for _, project := range s.Construction {
+		if project.Blueprint == blueprint {
+			return true
+		}
+	}
+
+	return false
+}
+
+// innerDefense returns what the settlement's inner fortifications add to its AC when no outer
+// wall stands in front of them.
+func (s *Settlement) innerDefense() int {
+	exposed := *s
+	exposed.Fortifications = nil
+
+	for _, fortification := range s.Fortifications {
+		if !FortificationRules[fortification.Type].Outer {
+			exposed.Fortifications = append(exposed.Fortifications, fortification)
+		}
+	}
+
+	defense := 0
+	for _, effect := range exposed.FortificationEffects() {
+		if effect.Counted && FortificationRules[effect.Fortification.Type].Inner {
+			defense += effect.Fortification.DefenseModifier
+		}
+	}
+
+	return defense
+}
+
+// ConstructionDescription describes the settlement's construction queue.
+func (s *Settlement) ConstructionDescription() string {
+	if len(s.Construction) == 0 {
+		return "None"
+	}
+
+	var projects []string
+	for _, project := range s.Construction {
+		projects = append(projects, fmt.Sprintf("%s (%d turns left)", project.Blueprint, project.TurnsLeft))
+	}
+
+	return strings.Join(projects, ", ")
+}
+
+// queueConstruction checks that the actor may build the blueprint at the settlement and, if so,
+// pays for it and adds it to the settlement's construction queue. Outer walls that are no
+// stronger than the walls they would stand in front of are refused, since the inner walls stop
+// counting once an outer wall stands.
+func (s *World) queueConstruction(actor *WorldActor, settlement *Settlement, name string) error {
+	blueprint, found := s.Blueprints()[name]
+	if !found {
+		return fmt.Errorf("there is no blueprint named %s", name)
+	} else if settlement.FortificationNamed(name) != nil {
+		return fmt.Errorf("settlement %s already has %s", settlement.Name, name)
+	} else if settlement.Building(name) {
+		return fmt.Errorf("settlement %s is already building %s", settlement.Name, name)
+	} else if len(blueprint.Upgrades) > 0 && settlement.FortificationNamed(blueprint.Upgrades) == nil {
+		return fmt.Errorf("%s upgrades %s, which settlement %s doesn't have", name, blueprint.Upgrades, settlement.Name)
+	} else if inner := settlement.innerDefense(); FortificationRules[blueprint.Type].Outer && blueprint.DefenseModifier <= inner {
+		return fmt.Errorf("%s would only add %d to the AC of settlement %s, hiding walls worth %d", name, blueprint.DefenseModifier, settlement.Name, inner)
+	} else if !actor.Afford(blueprint.Price()) {
+		return fmt.Errorf("%s costs %s but %s only holds %s", name, blueprint.Price(), actor.Name, actor.Holdings())
+	}
+
+	actor.Spend(blueprint.Price())
+	settlement.Construction = append(settlement.Construction, ConstructionProject{
+		Blueprint: name,
+		TurnsLeft: blueprint.Turns,
+	})
+
+	return nil
+}
+
+// completeConstruction adds a finished fortification to the settlement, replacing the one it
+// upgrades.
+func (s *World) completeConstruction(settlement *Settlement, name string) {
+	blueprint := s.Blueprints()[name]
+	fortification := blueprint.Fortification(name)
+
+	if upgraded := settlement.FortificationNamed(blueprint.Upgrades); len(blueprint.Upgrades) > 0 && upgraded != nil {
+		*upgraded = fortification
+	} else {
+		settlement.Fortifications = append(settlement.Fortifications, fortification)
+	}
+}
+
+// stepConstruction works on the first project in every settlement's construction queue. Work
+// can't go on while enemy armies are in the settlement.
+func (s *World) stepConstruction(log *DocumentElement) bool {
+	var (
+		activityObserved = false
+		constructionList = log.Element(UnorderedList)
+	)
+
+	constructionList.Attributes["style"] = "list-style-type: none;"
+
+	for _, settlement := range SettlementListFromMap(s.Settlements).Sorted() {
+		if len(settlement.Construction) == 0 {
+			continue
+		}
+
+		project := &settlement.Construction[0]
+
+		if enemies := s.EnemiesAt(settlement.Name, settlement.Allegiance); len(enemies) > 0 {
+			constructionList.Element(ListItem).Text = fmt.Sprintf("Construction of %s at %s is halted while army %s is present.",
+				project.Blueprint, NameLink(settlement.Name), NameLink(enemies[0].Name))
+			continue
+		}
+
+		activityObserved = true
+
+		if project.TurnsLeft--; project.TurnsLeft > 0 {
+			constructionList.Element(ListItem).Text = fmt.Sprintf("%s works on its %s (%d turns left).",
+				NameLink(settlement.Name), project.Blueprint, project.TurnsLeft)
+			continue
+		}
+
+		s.completeConstruction(settlement, project.Blueprint)
+		constructionList.Element(ListItem).Text = fmt.Sprintf("%s has finished building its %s.",
+			NameLink(settlement.Name), project.Blueprint)
+
+		settlement.Construction = settlement.Construction[1:]
+	}
+
+	return activityObserved
+}
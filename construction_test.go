@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// build orders the blueprint at the settlement for Aundair and works on it for the given turns.
+func build(t *testing.T, world *World, settlement, blueprint string, turns int) {
+	t.Helper()
+
+	if err := world.applyOrder("Aundair", Order{Settlement: settlement, Type: BuildOrder, Target: blueprint}); err != nil {
+		t.Fatalf("building %s at %s: %v", blueprint, settlement, err)
+	}
+
+	for turn := 0; turn < turns; turn++ {
+		world.stepConstruction(Element(Division))
+	}
+}
+
+func TestConstruction(t *testing.T) {
+	var (
+		world   = newTestWorld()
+		aundair = world.Actors["Aundair"]
+		wroat   = world.Settlements["Wroat"]
+	)
+
+	aundair.Treasury = 1000
+	aundair.Materials = 500
+
+	build(t, world, "Wroat", "Wooden Walls", 2)
+	if holdings := aundair.Holdings(); holdings != (Resources{Gold: 900, Food: 100, Materials: 470}) {
+		t.Errorf("Aundair holds %s after paying for wooden walls", holdings)
+	} else if wroat.FortificationNamed("Wooden Walls") != nil {
+		t.Errorf("wooden walls were finished after two of their three turns")
+	}
+
+	world.stepConstruction(Element(Division))
+	if walls := wroat.FortificationNamed("Wooden Walls"); walls == nil || len(wroat.Construction) > 0 {
+		t.Fatalf("wooden walls were not finished after three turns")
+	} else if walls.HP.Current != walls.HP.Max || walls.DefenseModifier != 10 {
+		t.Errorf("wooden walls were built with %d / %d HP and a defense of %d", walls.HP.Current, walls.HP.Max, walls.DefenseModifier)
+	}
+
+	build(t, world, "Wroat", "Stone Walls", 6)
+	if wroat.FortificationNamed("Wooden Walls") != nil || wroat.FortificationNamed("Stone Walls") == nil || len(wroat.Fortifications) != 1 {
+		t.Errorf("Wroat has %v after upgrading its walls, expected only stone walls", wroat.Fortifications)
+	}
+}
+
+func TestConstructionRejected(t *testing.T) {
+	world := newTestWorld()
+	world.Actors["Aundair"].Materials = 500
+	world.Settlements["Wroat"].Fortifications = []Fortification{defaultBlueprints["Stone Walls"].Fortification("Stone Walls")}
+	world.Settlements["Thaliost"].Construction = []ConstructionProject{{Blueprint: "Barracks", TurnsLeft: 2}}
+
+	tests := []struct {
+		settlement string
+		blueprint  string
+		rejected   string
+	}{
+		{settlement: "Wroat", blueprint: "Moat", rejected: "there is no blueprint named Moat"},
+		{settlement: "Wroat", blueprint: "Stone Walls", rejected: "settlement Wroat already has Stone Walls"},
+		{settlement: "Thaliost", blueprint: "Barracks", rejected: "settlement Thaliost is already building Barracks"},
+		{settlement: "Aelyndar", blueprint: "Stone Walls", rejected: "Stone Walls upgrades Wooden Walls, which settlement Aelyndar doesn't have"},
+		{settlement: "Wroat", blueprint: "Wooden Outer Walls", rejected: "Wooden Outer Walls would only add 10 to the AC of settlement Wroat, hiding walls worth 15"},
+		{settlement: "Aelyndar", blueprint: "Wooden Outer Walls", rejected: "Wooden Outer Walls costs 250 gold, 0 food, 100 materials but Aundair only holds 100 gold, 100 food, 500 materials"},
+		{settlement: "Olath", blueprint: "Barracks", rejected: "Aundair does not hold a settlement named Olath"},
+	}
+
+	for _, test := range tests {
+		if err := world.applyOrder("Aundair", Order{Settlement: test.settlement, Type: BuildOrder, Target: test.blueprint}); err == nil || !strings.Contains(err.Error(), test.rejected) {
+			t.Errorf("building %s at %s failed with %v, expected %q", test.blueprint, test.settlement, err, test.rejected)
+		}
+	}
+
+	world.Actors["Aundair"].Treasury = 1000
+	build(t, world, "Aelyndar", "Wooden Outer Walls", 0)
+}
+
+func TestConstructionHaltsWithEnemiesPresent(t *testing.T) {
+	world := newTestSiege(StanceSiege)
+	olath := world.Settlements["Olath"]
+	olath.Construction = []ConstructionProject{{Blueprint: "Barracks", TurnsLeft: 2}}
+
+	if world.stepConstruction(Element(Division)); olath.Construction[0].TurnsLeft != 2 {
+		t.Errorf("Olath worked on its barracks with an army besieging it")
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+const (
+	defaultCriticalHitFace  = 20
+	defaultCriticalMissFace = 1
+)
+
+// Rules holds the optional rules a world may turn on. Blueprints, Recruits, Economy, Healing and
+// Morale replace the fortifications that may be built, the armies that may be raised and the
+// default economy, healing and morale rules when given.
+type Rules struct {
+	Criticals  *CriticalRules
+	Blueprints map[string]Blueprint
+	Recruits   map[string]RecruitArchetype
+	Economy    *EconomyRules
+	Healing    *HealingRules
+	Morale     *MoraleRules
+}
+
+// Fumble is an entry on the fumble table, rolled when an attacker rolls a natural miss. Damage
+// is the HP the fumbling army or settlement loses, if any, and a fumbling army loses Morale on
+// top of the morale every fumble costs it.
+type Fumble struct {
+	Name   string
+	Damage RollSpec
+	Morale int `toml:",omitempty"`
+}
+
+// CriticalRules makes a natural roll of HitFace or higher on an attack's d20 hit automatically
+// and roll double damage dice, while a natural roll of MissFace or lower misses automatically
+// and rolls on the fumble table if there is one.
+type CriticalRules struct {
+	HitFace     int
+	MissFace    int
+	FumbleTable []Fumble
+}
+
+func (s *CriticalRules) hitFace() int {
+	if s.HitFace == 0 {
+		return defaultCriticalHitFace
+	}
+
+	return s.HitFace
+}
+
+func (s *CriticalRules) missFace() int {
+	if s.MissFace == 0 {
+		return defaultCriticalMissFace
+	}
+
+	return s.MissFace
+}
+
+// Criticals returns the critical rules for this world, or nil if they aren't in use.
+func (s *World) Criticals() *CriticalRules {
+	if s.Rules == nil {
+		return nil
+	}
+
+	return s.Rules.Criticals
+}
+
+type AttackResult int
+
+const (
+	AttackMiss        = AttackResult(0)
+	AttackHit         = AttackResult(1)
+	AttackCriticalHit = AttackResult(2)
+	AttackFumble      = AttackResult(3)
+)
+
+// rollAttack rolls an attack against an AC and decides whether it hit, taking natural rolls into
+// account when critical rules are in use.
+func (s *World) rollAttack(roller *Roller, attackRoll RollSpec, description string, ac int) (*RollRecord, AttackResult, error) {
+	attack, err := roller.Roll(attackRoll, description, ac)
+	if err != nil {
+		return nil, AttackMiss, err
+	}
+
+	if criticals := s.Criticals(); criticals != nil {
+		if natural, found := attack.Natural(); found && natural >= criticals.hitFace() {
+			return attack, AttackCriticalHit, nil
+		} else if found && natural <= criticals.missFace() {
+			return attack, AttackFumble, nil
+		}
+	}
+
+	if attack.Total >= ac {
+		return attack, AttackHit, nil
+	}
+
+	return attack, AttackMiss, nil
+}
+
+// rollDamage rolls damage for a hit, doubling the damage dice for a critical hit.
+func (s *World) rollDamage(roller *Roller, damageRoll RollSpec, description string, result AttackResult) (*RollRecord, error) {
+	if result == AttackCriticalHit {
+		return roller.RollCritical(damageRoll, description)
+	}
+
+	return roller.Roll(damageRoll, description, 0)
+}
+
+// rollFumble picks an entry from the fumble table for an attacker that rolled a natural miss. The
+// rolls made are returned so that they can be shown alongside the attack. Returns nil if there is
+// no fumble table.
+func (s *World) rollFumble(roller *Roller, attacker string) (*Fumble, int, []*RollRecord, error) {
+	criticals := s.Criticals()
+	if criticals == nil || len(criticals.FumbleTable) == 0 {
+		return nil, 0, nil, nil
+	}
+
+	tableRoll := RollSpec{Die(fmt.Sprintf("d%d", len(criticals.FumbleTable)))}
+	if entry, err := roller.Roll(tableRoll, fmt.Sprintf("%s rolls on the fumble table", attacker), 0); err != nil {
+		return nil, 0, nil, err
+	} else {
+		fumble := &criticals.FumbleTable[entry.Total-1]
+		if len(fumble.Damage) == 0 {
+			return fumble, 0, []*RollRecord{entry}, nil
+		}
+
+		if damage, err := roller.Roll(fumble.Damage, fmt.Sprintf("%s suffers %s", attacker, fumble.Name), 0); err != nil {
+			return nil, 0, nil, fmt.Errorf("fumble %s: %v", fumble.Name, err)
+		} else {
+			return fumble, damage.Total, []*RollRecord{entry, damage}, nil
+		}
+	}
+}
+
+// describeFumble writes the combat log entry for a fumbled attack, along with the HP and morale it
+// cost the attacker.
+func describeFumble(entry *DocumentElement, fumble *Fumble, damage, morale int) {
+	if fumble == nil {
+		return
+	}
+
+	var losses []string
+	if damage > 0 {
+		losses = append(losses, fmt.Sprintf("%d HP", damage))
+	}
+
+	if morale > 0 {
+		losses = append(losses, fmt.Sprintf("%d morale", morale))
+	}
+
+	if len(losses) > 0 {
+		entry.Text = fmt.Sprintf("%s %s, losing %s.", entry.Text, fumble.Name, strings.Join(losses, " and "))
+	} else {
+		entry.Text = fmt.Sprintf("%s %s.", entry.Text, fumble.Name)
+	}
+}
+
+// withNatural returns a copy of an expression with the given term replaced by a flat value.
+func withNatural(expr RollExpr, term *DiceTerm, value int) RollExpr {
+	switch typed := expr.(type) {
+	case *DiceTerm:
+		if typed == term {
+			return &Constant{Value: value}
+		}
+	case *BinaryExpr:
+		return &BinaryExpr{Operator: typed.Operator, Left: withNatural(typed.Left, term, value), Right: withNatural(typed.Right, term, value)}
+	case *Negation:
+		return &Negation{Inner: withNatural(typed.Inner, term, value)}
+	}
+
+	return expr
+}
+
+// naturalTerm finds the first term of an expression whose face decides criticals, mirroring
+// the order in which RollRecord.Natural finds it.
+func naturalTerm(expr RollExpr) *DiceTerm {
+	switch typed := expr.(type) {
+	case *DiceTerm:
+		if typed.DecidesNatural() {
+			return typed
+		}
+	case *BinaryExpr:
+		if term := naturalTerm(typed.Left); term != nil {
+			return term
+		}
+
+		return naturalTerm(typed.Right)
+	case *Negation:
+		return naturalTerm(typed.Inner)
+	}
+
+	return nil
+}
+
+// sampleHitChance estimates the chance of an attack roll hitting the given AC by making the
+// attack many times, for rolls too large to work out exactly.
+func (s *World) sampleHitChance(attackRoll RollSpec, ac int) (float64, error) {
+	var (
+		roller = NewRoller(rand.New(rand.NewSource(distributionSeed)))
+		hits   = 0
+	)
+
+	for idx := 0; idx < distributionSamples; idx++ {
+		if _, result, err := s.rollAttack(roller, attackRoll, "", ac); err != nil {
+			return 0, err
+		} else if result == AttackHit || result == AttackCriticalHit {
+			hits++
+		}
+	}
+
+	return float64(hits) / distributionSamples, nil
+}
+
+// HitChance returns the chance of an attack roll hitting the given AC under this world's rules.
+// With critical rules in use the chance is worked out for each natural face of the deciding d20
+// separately, since natural hits and misses ignore the total. Returns true along with the chance
+// if it was estimated from sample attacks.
+func (s *World) HitChance(attackRoll RollSpec, ac int) (float64, bool, error) {
+	criticals := s.Criticals()
+	if criticals == nil {
+		return attackRoll.HitChance(ac)
+	}
+
+	bounds, exprs, err := attackRoll.parseBounds()
+	if err != nil {
+		return 0, false, err
+	} else if bounds.work > maxDistributionWork {
+		chance, err := s.sampleHitChance(attackRoll, ac)
+		return chance, true, err
+	}
+
+	var (
+		natural  *DiceTerm
+		deciding = -1
+	)
+
+	for idx, expr := range exprs {
+		if natural = naturalTerm(expr); natural != nil {
+			deciding = idx
+			break
+		}
+	}
+
+	if deciding < 0 {
+		return attackRoll.HitChance(ac)
+	}
+
+	rest := PointDistribution(0)
+	for idx, expr := range exprs {
+		if idx != deciding {
+			rest = rest.Add(expr.Distribution())
+		}
+	}
+
+	chance := 0.0
+	naturals := natural.Distribution()
+
+	for face := naturals.Min(); face <= naturals.Max(); face++ {
+		probability := naturals.Probability(face)
+
+		if face >= criticals.hitFace() {
+			chance += probability
+		} else if face > criticals.missFace() {
+			total := withNatural(exprs[deciding], natural, face).Distribution().Add(rest)
+			chance += probability * total.ChanceAtLeast(ac)
+		}
+	}
+
+	return chance, false, nil
+}
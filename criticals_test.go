@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestRollAttackNaturals(t *testing.T) {
+	var (
+		world  = newTestWorld()
+		roller = NewRoller(rand.New(rand.NewSource(1)))
+		seen   = make(map[AttackResult]bool)
+	)
+
+	world.Rules = &Rules{Criticals: &CriticalRules{}}
+
+	// d20+30 always beats AC 20 and d20-30 never does, so only the natural faces can change that
+	for _, modifier := range []string{"+30", "-30"} {
+		for idx := 0; idx < 200; idx++ {
+			attack, result, err := world.rollAttack(roller, RollSpec{Die("d20" + modifier)}, "", 20)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			natural, _ := attack.Natural()
+			expected := AttackHit
+			if natural == 20 {
+				expected = AttackCriticalHit
+			} else if natural == 1 {
+				expected = AttackFumble
+			} else if modifier == "-30" {
+				expected = AttackMiss
+			}
+
+			if result != expected {
+				t.Errorf("natural %d on d20%s against AC 20 gave result %d, expected %d", natural, modifier, result, expected)
+			}
+
+			seen[result] = true
+		}
+	}
+
+	if len(seen) != 4 {
+		t.Errorf("only saw results %v, expected hits, misses, critical hits and fumbles", seen)
+	}
+}
+
+func TestArmyFumbles(t *testing.T) {
+	var (
+		world  = newTestWorld()
+		army   = world.Armies["First Host"]
+		entry  = Element(ListItem)
+		roller = NewRoller(rand.New(rand.NewSource(1)))
+	)
+
+	entry.Text = "Army First Host fumbles."
+	world.Rules = &Rules{Criticals: &CriticalRules{
+		FumbleTable: []Fumble{{Name: "drops its standard", Damage: RollSpec{Die("5")}, Morale: 20}},
+	}}
+
+	if err := world.armyFumbles(roller, army, entry, Element(UnorderedList)); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedMorale := maxMorale - defaultMorale.damageLoss(army, 5) - defaultMorale.FumbleLoss - 20
+	if army.HP.Current != 45 {
+		t.Errorf("army has %d HP after its fumble, expected 45", army.HP.Current)
+	}
+
+	if army.Morale != expectedMorale {
+		t.Errorf("army has %d morale after its fumble, expected %d", army.Morale, expectedMorale)
+	}
+
+	if !strings.Contains(entry.Text, "drops its standard, losing 5 HP and 20 morale.") {
+		t.Errorf("fumble is described as %q", entry.Text)
+	}
+}
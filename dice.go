@@ -3,108 +3,553 @@ package main
 import (
 	"fmt"
 	"math/rand"
-	"strconv"
 	"strings"
 )
 
-func parseFacesAndModifier(facesModifierStr string) (int, int, error) {
-	const (
-		dieFaces    = 0
-		dieModifier = 1
-	)
+const (
+	// Limits that keep rolls and their distributions to a sensible size
+	maxDiceCount = 100
+	maxDieSides  = 1000
+
+	// Exploding dice stop exploding after this many extra rolls of a single die
+	maxExplosions = 10
+)
+
+// RollExpr is a node in the syntax tree of a parsed roll expression. Rolling an expression
+// records every die and modifier that contributed to the result into the given record.
+type RollExpr interface {
+	Roll(rng *rand.Rand, record *RollRecord) int
+	Distribution() Distribution
+	String() string
+}
+
+// Constant is a flat number in a roll expression.
+type Constant struct {
+	Value int
+}
+
+func (s *Constant) Roll(rng *rand.Rand, record *RollRecord) int {
+	record.Modifiers = append(record.Modifiers, s.Value)
+	return s.Value
+}
+
+func (s *Constant) String() string {
+	return fmt.Sprintf("%d", s.Value)
+}
+
+type KeepRule int
+
+const (
+	KeepAll     = KeepRule(0)
+	KeepHighest = KeepRule(1)
+	KeepLowest  = KeepRule(2)
+)
+
+// DiceTerm is a group of identical dice such as 4d6kh3. Each die is rolled, rerolled once if it
+// shows RerollBelow or less, and rolled again and added on if it explodes. The dice kept by the
+// keep rule are then summed.
+type DiceTerm struct {
+	Count       int
+	Sides       int
+	Keep        KeepRule
+	KeepCount   int
+	Explode     bool
+	RerollBelow int
+}
+
+func (s *DiceTerm) rollDie(rng *rand.Rand) DieRecord {
+	face := rng.Intn(s.Sides) + 1
+	die := DieRecord{
+		Faces: []int{face},
+	}
+
+	if face <= s.RerollBelow {
+		face = rng.Intn(s.Sides) + 1
+
+		die.Faces = append(die.Faces, face)
+		die.Rerolled = true
+	}
+
+	die.Value = face
+	if s.Explode {
+		for explosions := 0; face == s.Sides && explosions < maxExplosions; explosions++ {
+			face = rng.Intn(s.Sides) + 1
+
+			die.Faces = append(die.Faces, face)
+			die.Value += face
+		}
+	}
 
+	return die
+}
+
+// kept returns the indexes of the dice that count towards the total for this term.
+func (s *DiceTerm) kept(faces []int) []int {
+	indexes := make([]int, len(faces))
+	for idx := range faces {
+		indexes[idx] = idx
+	}
+
+	if s.Keep == KeepAll {
+		return indexes
+	}
+
+	// Insertion sort the indexes by face, highest first for keep highest and lowest first for
+	// keep lowest. Ties keep their rolled order.
+	for i := 1; i < len(indexes); i++ {
+		for j := i; j > 0; j-- {
+			left, right := faces[indexes[j-1]], faces[indexes[j]]
+			if (s.Keep == KeepHighest && right > left) || (s.Keep == KeepLowest && right < left) {
+				indexes[j-1], indexes[j] = indexes[j], indexes[j-1]
+			} else {
+				break
+			}
+		}
+	}
+
+	return indexes[:s.KeepCount]
+}
+
+func (s *DiceTerm) Roll(rng *rand.Rand, record *RollRecord) int {
 	var (
-		err      error
-		faces    int64 = 0
-		modifier int64 = 0
+		dice   = make([]DieRecord, s.Count)
+		values = make([]int, s.Count)
 	)
 
-	// Check for additive modifiers first
-	splitStr := "+"
-	hasModifier := strings.Contains(facesModifierStr, splitStr)
+	for idx := range dice {
+		dice[idx] = s.rollDie(rng)
+		dice[idx].Dropped = true
+		values[idx] = dice[idx].Value
+	}
+
+	total := 0
+	for _, idx := range s.kept(values) {
+		dice[idx].Dropped = false
+		total += dice[idx].Value
+	}
+
+	diceRecord := DiceRecord{
+		Term:  s.String(),
+		Sides: s.Sides,
+		Dice:  dice,
+	}
+
+	if s.DecidesNatural() {
+		diceRecord.Natural = total
+	}
+
+	record.Terms = append(record.Terms, diceRecord)
+	return total
+}
+
+// DecidesNatural returns true if this term is a single d20, or a group of d20s that keeps one
+// die such as 2d20kh1, whose face decides critical hits and fumbles.
+func (s *DiceTerm) DecidesNatural() bool {
+	return s.Sides == 20 && !s.Explode && (s.Count == 1 || (s.Keep != KeepAll && s.KeepCount == 1))
+}
+
+// doubled returns a copy of this term that rolls twice as many dice, keeping twice as many.
+func (s *DiceTerm) doubled() *DiceTerm {
+	doubled := *s
+	doubled.Count *= 2
+	doubled.KeepCount *= 2
+
+	return &doubled
+}
+
+func (s *DiceTerm) String() string {
+	output := strings.Builder{}
+	if s.Count != 1 {
+		output.WriteString(fmt.Sprintf("%d", s.Count))
+	}
+
+	output.WriteString(fmt.Sprintf("d%d", s.Sides))
 
-	if !hasModifier {
-		// If we didn't find an additive modifier see if there's a subtractive one
-		splitStr = "-"
-		hasModifier = strings.Contains(facesModifierStr, splitStr)
+	if s.Explode {
+		output.WriteString("!")
 	}
 
-	if hasModifier {
-		if facesModifierParts := strings.Split(facesModifierStr, splitStr); len(facesModifierParts) != 2 {
-			return 0, 0, fmt.Errorf("expected two values but got %v", facesModifierParts)
-		} else if faces, err = strconv.ParseInt(facesModifierParts[dieFaces], 10, 32); err != nil {
-			return 0, 0, err
-		} else if modifier, err = strconv.ParseInt(facesModifierParts[dieModifier], 10, 32); err != nil {
-			return 0, 0, err
+	if s.RerollBelow > 0 {
+		output.WriteString(fmt.Sprintf("r%d", s.RerollBelow))
+	}
+
+	switch s.Keep {
+	case KeepHighest:
+		output.WriteString(fmt.Sprintf("kh%d", s.KeepCount))
+	case KeepLowest:
+		output.WriteString(fmt.Sprintf("kl%d", s.KeepCount))
+	}
+
+	return output.String()
+}
+
+// BinaryExpr combines two expressions with +, - or *.
+type BinaryExpr struct {
+	Operator rune
+	Left     RollExpr
+	Right    RollExpr
+}
+
+func (s *BinaryExpr) Roll(rng *rand.Rand, record *RollRecord) int {
+	switch s.Operator {
+	case '+':
+		return s.Left.Roll(rng, record) + s.Right.Roll(rng, record)
+
+	case '-':
+		left := s.Left.Roll(rng, record)
+		return left - record.subtract(s.Right, rng)
+
+	case '*':
+		// Flat numbers in a product are multipliers rather than modifiers
+		left, right := record.multiply(s.Left, rng), record.multiply(s.Right, rng)
+		return left * right
+	}
+
+	panic(fmt.Sprintf("Unknown roll operator %q.", s.Operator))
+}
+
+func (s *BinaryExpr) String() string {
+	return fmt.Sprintf("(%s%c%s)", s.Left, s.Operator, s.Right)
+}
+
+// doubleDice returns a copy of an expression with every dice term rolling twice as many dice.
+// Flat modifiers are left alone. This is used to roll damage for critical hits.
+func doubleDice(expr RollExpr) RollExpr {
+	switch typed := expr.(type) {
+	case *DiceTerm:
+		return typed.doubled()
+	case *BinaryExpr:
+		return &BinaryExpr{Operator: typed.Operator, Left: doubleDice(typed.Left), Right: doubleDice(typed.Right)}
+	case *Negation:
+		return &Negation{Inner: doubleDice(typed.Inner)}
+	}
+
+	return expr
+}
+
+// Negation flips the sign of an expression, as in -d4.
+type Negation struct {
+	Inner RollExpr
+}
+
+func (s *Negation) Roll(rng *rand.Rand, record *RollRecord) int {
+	return -record.subtract(s.Inner, rng)
+}
+
+func (s *Negation) String() string {
+	return fmt.Sprintf("-%s", s.Inner)
+}
+
+// ParseError describes where and why a roll expression could not be parsed.
+type ParseError struct {
+	Expression string
+	Position   int
+	Message    string
+}
+
+func (s *ParseError) Error() string {
+	return fmt.Sprintf("%q is not a valid roll: %s at position %d", s.Expression, s.Message, s.Position+1)
+}
+
+// rollParser is a recursive descent parser for roll expressions:
+//
+//	expression := product (('+' | '-') product)*
+//	product    := unary ('*' unary)*
+//	unary      := '-' unary | primary
+//	primary    := number | dice | '(' expression ')'
+//	dice       := [number] 'd' (number | '%') modifier*
+//	modifier   := '!' | 'r' number | 'k' ['h' | 'l'] number
+type rollParser struct {
+	source string
+	input  []rune
+	pos    int
+}
+
+func (s *rollParser) fail(format string, args ...interface{}) error {
+	return &ParseError{
+		Expression: s.source,
+		Position:   s.pos,
+		Message:    fmt.Sprintf(format, args...),
+	}
+}
+
+func (s *rollParser) skipSpace() {
+	for s.pos < len(s.input) && (s.input[s.pos] == ' ' || s.input[s.pos] == '\t') {
+		s.pos++
+	}
+}
+
+func (s *rollParser) peek() rune {
+	if s.skipSpace(); s.pos < len(s.input) {
+		return s.input[s.pos]
+	}
+
+	return 0
+}
+
+func (s *rollParser) accept(char rune) bool {
+	if s.peek() == char {
+		s.pos++
+		return true
+	}
+
+	return false
+}
+
+func isDigit(char rune) bool {
+	return char >= '0' && char <= '9'
+}
+
+func (s *rollParser) number() (int, error) {
+	if !isDigit(s.peek()) {
+		return 0, s.fail("expected a number")
+	}
+
+	value := 0
+	for ; s.pos < len(s.input) && isDigit(s.input[s.pos]); s.pos++ {
+		if value = value*10 + int(s.input[s.pos]-'0'); value > 1000000 {
+			return 0, s.fail("number is too large")
 		}
+	}
 
-		if splitStr == "-" {
-			// Make sure to make the modifier negative if this is a subtractive modifier
-			modifier *= -1
+	return value, nil
+}
+
+func (s *rollParser) expression() (RollExpr, error) {
+	left, err := s.product()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		operator := s.peek()
+		if operator != '+' && operator != '-' {
+			return left, nil
+		}
+
+		s.pos++
+		if right, err := s.product(); err != nil {
+			return nil, err
+		} else {
+			left = &BinaryExpr{Operator: operator, Left: left, Right: right}
+		}
+	}
+}
+
+func (s *rollParser) product() (RollExpr, error) {
+	left, err := s.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for s.accept('*') {
+		if right, err := s.unary(); err != nil {
+			return nil, err
+		} else {
+			left = &BinaryExpr{Operator: '*', Left: left, Right: right}
 		}
-	} else if faces, err = strconv.ParseInt(facesModifierStr, 10, 32); err != nil {
-		return 0, 0, err
 	}
 
-	return int(faces), int(modifier), nil
+	return left, nil
 }
 
-var (
-	D20 = Die("d20")
-)
+func (s *rollParser) unary() (RollExpr, error) {
+	if s.accept('-') {
+		if inner, err := s.unary(); err != nil {
+			return nil, err
+		} else {
+			return &Negation{Inner: inner}, nil
+		}
+	}
 
-type Die string
+	return s.primary()
+}
 
-func (s Die) String() string {
-	return strings.ToLower(strings.TrimSpace(string(s)))
+func (s *rollParser) primary() (RollExpr, error) {
+	switch next := s.peek(); {
+	case next == '(':
+		s.pos++
+
+		if inner, err := s.expression(); err != nil {
+			return nil, err
+		} else if !s.accept(')') {
+			return nil, s.fail("expected a closing parenthesis")
+		} else {
+			return inner, nil
+		}
+
+	case next == 'd':
+		return s.dice(1, s.pos)
+
+	case isDigit(next):
+		start := s.pos
+
+		if value, err := s.number(); err != nil {
+			return nil, err
+		} else if s.peek() == 'd' {
+			return s.dice(value, start)
+		} else {
+			return &Constant{Value: value}, nil
+		}
+
+	case next == 0:
+		return nil, s.fail("unexpected end of roll")
+
+	default:
+		return nil, s.fail("unexpected %q", next)
+	}
 }
 
-func (s Die) Roll() (int, error) {
-	const (
-		numDicePart          = 0
-		facesAndModifierPart = 1
-	)
+func (s *rollParser) dice(count, start int) (RollExpr, error) {
+	// Skip past the 'd'
+	s.pos++
 
-	// Start with the default of one die
-	numDice := 1
+	term := &DiceTerm{
+		Count: count,
+	}
 
-	if parts := strings.Split(s.String(), "d"); len(parts) != 2 {
-		return 0, fmt.Errorf("%s is not a valid roll", s)
+	if s.accept('%') {
+		term.Sides = 100
+	} else if sides, err := s.number(); err != nil {
+		return nil, err
 	} else {
-		if len(parts[numDicePart]) > 0 {
-			if parsedNumDice, err := strconv.ParseInt(parts[numDicePart], 10, 32); err != nil {
-				return 0, fmt.Errorf("%s is not a valid roll: %v", s, err)
+		term.Sides = sides
+	}
+
+	for {
+		switch {
+		case s.accept('!'):
+			term.Explode = true
+
+		case s.accept('r'):
+			if below, err := s.number(); err != nil {
+				return nil, err
 			} else {
-				numDice = int(parsedNumDice)
+				term.RerollBelow = below
 			}
-		}
 
-		if faces, modifier, err := parseFacesAndModifier(parts[facesAndModifierPart]); err != nil {
-			return 0, fmt.Errorf("%s is not a valid roll: %v", s, err)
-		} else {
-			total := 0
-			for roll := 0; roll < numDice; roll++ {
-				total += rand.Int()%faces + 1 + modifier
+		case s.accept('k'):
+			term.Keep = KeepHighest
+			if s.accept('l') {
+				term.Keep = KeepLowest
+			} else {
+				s.accept('h')
+			}
+
+			if keepCount, err := s.number(); err != nil {
+				return nil, err
+			} else {
+				term.KeepCount = keepCount
+			}
+
+		default:
+			// Point any errors in the term at the start of the term
+			end := s.pos
+			s.pos = start
+
+			if err := s.checkDice(term); err != nil {
+				return nil, err
 			}
 
-			return total, nil
+			s.pos = end
+			return term, nil
 		}
 	}
 }
 
+func (s *rollParser) checkDice(term *DiceTerm) error {
+	if term.Count < 1 || term.Count > maxDiceCount {
+		return s.fail("dice count must be between 1 and %d", maxDiceCount)
+	} else if term.Sides < 1 || term.Sides > maxDieSides {
+		return s.fail("dice must have between 1 and %d sides", maxDieSides)
+	} else if term.Explode && term.Sides < 2 {
+		return s.fail("single sided dice can't explode")
+	} else if term.RerollBelow >= term.Sides {
+		return s.fail("rerolling %d or lower on a d%d would reroll every face", term.RerollBelow, term.Sides)
+	} else if term.Keep != KeepAll && (term.KeepCount < 1 || term.KeepCount > term.Count) {
+		return s.fail("can only keep between 1 and %d dice", term.Count)
+	}
+
+	return nil
+}
+
+// ParseRoll parses a roll expression such as "2d6+1d4+3", "4d6kh3" or "(d6!+2)*2".
+func ParseRoll(source string) (RollExpr, error) {
+	parser := &rollParser{
+		source: source,
+		input:  []rune(strings.ToLower(source)),
+	}
+
+	if expr, err := parser.expression(); err != nil {
+		return nil, err
+	} else if parser.peek() != 0 {
+		return nil, parser.fail("unexpected %q", parser.peek())
+	} else {
+		return expr, nil
+	}
+}
+
+var (
+	D20 = Die("d20")
+)
+
+type Die string
+
+func (s Die) String() string {
+	return strings.ToLower(strings.TrimSpace(string(s)))
+}
+
+func (s Die) Parse() (RollExpr, error) {
+	return ParseRoll(s.String())
+}
+
+func (s Die) Roll(rng *rand.Rand) (*RollRecord, error) {
+	return RollSpec{s}.Roll(rng)
+}
+
 type RollSpec []Die
 
-func (s RollSpec) Roll() (int, error) {
-	sum := 0
+// Check parses every die in the spec and returns the first parse error found.
+func (s RollSpec) Check() error {
+	for _, die := range s {
+		if _, err := die.Parse(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Roll rolls every die in the spec and sums them. The returned record holds the total along with
+// each die face and modifier that went into it.
+func (s RollSpec) Roll(rng *rand.Rand) (*RollRecord, error) {
+	return s.roll(rng, false)
+}
+
+// RollCritical rolls the spec with every dice term doubled, as for the damage of a critical hit.
+func (s RollSpec) RollCritical(rng *rand.Rand) (*RollRecord, error) {
+	return s.roll(rng, true)
+}
+
+func (s RollSpec) roll(rng *rand.Rand, critical bool) (*RollRecord, error) {
+	record := &RollRecord{
+		Expression: s.String(),
+		Critical:   critical,
+	}
+
 	for _, die := range s {
-		if nextRoll, err := die.Roll(); err != nil {
-			return 0, err
+		if expr, err := die.Parse(); err != nil {
+			return nil, err
 		} else {
-			sum += nextRoll
+			if critical {
+				expr = doubleDice(expr)
+			}
+
+			record.Total += expr.Roll(rng, record)
 		}
 	}
 
-	return sum, nil
+	return record, nil
 }
 
 func (s RollSpec) String() string {
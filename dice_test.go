@@ -0,0 +1,66 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRoll(t *testing.T) {
+	tests := []struct {
+		roll     string
+		expected string
+	}{
+		{roll: "d20", expected: "d20"},
+		{roll: "D20", expected: "d20"},
+		{roll: "d%", expected: "d100"},
+		{roll: "d20+6", expected: "(d20+6)"},
+		{roll: "2d6 + 1d4 + 3", expected: "((2d6+d4)+3)"},
+		{roll: "4d6kh3", expected: "4d6kh3"},
+		{roll: "4d6k3", expected: "4d6kh3"},
+		{roll: "2d20kl1", expected: "2d20kl1"},
+		{roll: "4d6r1kl2", expected: "4d6r1kl2"},
+		{roll: "(d6!+2)*2", expected: "((d6!+2)*2)"},
+		{roll: "-d4", expected: "-d4"},
+		{roll: "d8-1", expected: "(d8-1)"},
+	}
+
+	for _, test := range tests {
+		expr, err := ParseRoll(test.roll)
+		if err != nil {
+			t.Errorf("ParseRoll(%q) failed: %v", test.roll, err)
+		} else if parsed := expr.String(); parsed != test.expected {
+			t.Errorf("ParseRoll(%q) = %s, expected %s", test.roll, parsed, test.expected)
+		}
+	}
+}
+
+func TestParseRollRejectsBadRolls(t *testing.T) {
+	tests := []struct {
+		roll    string
+		message string
+	}{
+		{roll: "", message: "unexpected end of roll"},
+		{roll: "d0", message: "dice must have between 1 and 1000 sides"},
+		{roll: "d1001", message: "dice must have between 1 and 1000 sides"},
+		{roll: "0d6", message: "dice count must be between 1 and 100"},
+		{roll: "101d6", message: "dice count must be between 1 and 100"},
+		{roll: "d1!", message: "single sided dice can't explode"},
+		{roll: "d6r6", message: "would reroll every face"},
+		{roll: "4d6kh5", message: "can only keep between 1 and 4 dice"},
+		{roll: "4d6kh0", message: "can only keep between 1 and 4 dice"},
+		{roll: "2d6+", message: "unexpected end of roll"},
+		{roll: "d20x", message: "unexpected 'x'"},
+		{roll: "(d6", message: "expected"},
+		{roll: "9999999", message: "number is too large"},
+	}
+
+	for _, test := range tests {
+		if expr, err := ParseRoll(test.roll); err == nil {
+			t.Errorf("ParseRoll(%q) = %s, expected an error", test.roll, expr)
+		} else if _, isParseError := err.(*ParseError); !isParseError {
+			t.Errorf("ParseRoll(%q) returned %T, expected a *ParseError", test.roll, err)
+		} else if !strings.Contains(err.Error(), test.message) {
+			t.Errorf("ParseRoll(%q) failed with %q, expected it to mention %q", test.roll, err, test.message)
+		}
+	}
+}
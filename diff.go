@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeUpdated = "changed"
+)
+
+// Change is a single difference between two worlds, such as an army's HP dropping or a
+// settlement changing hands. Delta is the difference for numeric fields.
+type Change struct {
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+	Event string `json:"event"`
+	Field string `json:"field,omitempty"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+	Delta int    `json:"delta,omitempty"`
+}
+
+// describeValue shows an empty value as none, such as an army that had no target.
+func describeValue(value string) string {
+	if len(value) == 0 {
+		return "none"
+	}
+
+	return value
+}
+
+func (s Change) String() string {
+	subject := fmt.Sprintf("%s %s", strings.Title(s.Kind), s.Name)
+	from, to := describeValue(s.From), describeValue(s.To)
+
+	switch {
+	case s.Event != ChangeUpdated:
+		return fmt.Sprintf("%s was %s", subject, s.Event)
+	case s.Field == "Destroyed" && s.To == "true":
+		return fmt.Sprintf("%s was destroyed", subject)
+	case s.Field == "Location":
+		return fmt.Sprintf("%s moved from %s to %s", subject, from, to)
+	case s.Delta != 0:
+		return fmt.Sprintf("%s: %s %s → %s (%+d)", subject, s.Field, from, to, s.Delta)
+	}
+
+	return fmt.Sprintf("%s: %s %s → %s", subject, s.Field, from, to)
+}
+
+// diffField is a field compared between two versions of a settlement or army. Numeric fields
+// also report how much they changed by.
+type diffField struct {
+	Name    string
+	Numeric bool
+}
+
+var (
+	settlementDiffFields = []diffField{
+		{Name: "Allegiance"},
+		{Name: "Occupied"},
+		{Name: "HP", Numeric: true},
+		{Name: "Max HP", Numeric: true},
+		{Name: "AC", Numeric: true},
+		{Name: "Population", Numeric: true},
+		{Name: "War Guard"},
+		{Name: "Fortifications"},
+		{Name: "Construction"},
+		{Name: "Training"},
+	}
+
+	actorDiffFields = []diffField{
+		{Name: "Treasury", Numeric: true},
+		{Name: "Food", Numeric: true},
+		{Name: "Materials", Numeric: true},
+	}
+
+	armyDiffFields = []diffField{
+		{Name: "Allegiance"},
+		{Name: "Location"},
+		{Name: "Destination"},
+		{Name: "Route"},
+		{Name: "HP", Numeric: true},
+		{Name: "Max HP", Numeric: true},
+		{Name: "AC", Numeric: true},
+		{Name: "Target"},
+		{Name: "Stance"},
+		{Name: "Morale", Numeric: true},
+		{Name: "Routed"},
+		{Name: "Destroyed"},
+	}
+)
+
+func settlementDiffValues(settlement *Settlement) map[string]string {
+	var fortifications []string
+	for _, fortification := range settlement.Fortifications {
+		if fortification.Breached {
+			fortifications = append(fortifications, fmt.Sprintf("%s (breached)", fortification.Name))
+		} else if fortification.Damaged() {
+			fortifications = append(fortifications, fmt.Sprintf("%s (%d / %d HP)", fortification.Name, fortification.HP.Current, fortification.HP.Max))
+		} else {
+			fortifications = append(fortifications, fortification.Name)
+		}
+	}
+
+	return map[string]string{
+		"Allegiance":     settlement.Allegiance,
+		"Occupied":       strconv.FormatBool(settlement.Occupied),
+		"HP":             strconv.Itoa(settlement.HP.Current),
+		"Max HP":         strconv.Itoa(settlement.HP.Max),
+		"AC":             strconv.Itoa(settlement.AC()),
+		"Population":     strconv.FormatUint(uint64(settlement.Population), 10),
+		"War Guard":      strconv.FormatBool(settlement.HasWarGuard),
+		"Fortifications": strings.Join(fortifications, ", "),
+		"Construction":   settlement.ConstructionDescription(),
+		"Training":       settlement.TrainingDescription(),
+	}
+}
+
+func actorDiffValues(actor *WorldActor) map[string]string {
+	return map[string]string{
+		"Treasury":  strconv.Itoa(actor.Treasury),
+		"Food":      strconv.Itoa(actor.Food),
+		"Materials": strconv.Itoa(actor.Materials),
+	}
+}
+
+func armyDiffValues(army *Army) map[string]string {
+	return map[string]string{
+		"Allegiance":  army.Allegiance,
+		"Location":    army.LocationDescription(),
+		"Destination": army.Destination,
+		"Route":       army.RemainingRoute(),
+		"HP":          strconv.Itoa(army.HP.Current),
+		"Max HP":      strconv.Itoa(army.HP.Max),
+		"AC":          strconv.Itoa(army.AC),
+		"Target":      army.Target,
+		"Stance":      describeStance(army.Stance),
+		"Morale":      strconv.Itoa(army.Morale),
+		"Routed":      strconv.FormatBool(army.Routed),
+		"Destroyed":   strconv.FormatBool(army.Destroyed),
+	}
+}
+
+func diffValues(kind, name string, fields []diffField, before, after map[string]string) []Change {
+	var changes []Change
+	for _, field := range fields {
+		if before[field.Name] == after[field.Name] {
+			continue
+		}
+
+		change := Change{
+			Kind:  kind,
+			Name:  name,
+			Event: ChangeUpdated,
+			Field: field.Name,
+			From:  before[field.Name],
+			To:    after[field.Name],
+		}
+
+		if field.Numeric {
+			from, _ := strconv.Atoi(change.From)
+			to, _ := strconv.Atoi(change.To)
+			change.Delta = to - from
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// DiffWorlds lists every change to the settlements, armies and actors between two worlds, in
+// name order with settlements first and actors last.
+func DiffWorlds(before, after *World) []Change {
+	changes := []Change{}
+
+	for _, settlement := range SettlementListFromMap(before.Settlements).Sorted() {
+		if updated, found := after.Settlements[settlement.Name]; !found {
+			changes = append(changes, Change{Kind: "settlement", Name: settlement.Name, Event: ChangeRemoved})
+		} else {
+			changes = append(changes, diffValues("settlement", settlement.Name, settlementDiffFields,
+				settlementDiffValues(settlement), settlementDiffValues(updated))...)
+		}
+	}
+
+	for _, settlement := range SettlementListFromMap(after.Settlements).Sorted() {
+		if _, found := before.Settlements[settlement.Name]; !found {
+			changes = append(changes, Change{Kind: "settlement", Name: settlement.Name, Event: ChangeAdded})
+		}
+	}
+
+	for _, army := range ArmyListFromMap(before.Armies).Sorted() {
+		if updated, found := after.Armies[army.Name]; !found {
+			changes = append(changes, Change{Kind: "army", Name: army.Name, Event: ChangeRemoved})
+		} else {
+			changes = append(changes, diffValues("army", army.Name, armyDiffFields,
+				armyDiffValues(army), armyDiffValues(updated))...)
+		}
+	}
+
+	for _, army := range ArmyListFromMap(after.Armies).Sorted() {
+		if _, found := before.Armies[army.Name]; !found {
+			changes = append(changes, Change{Kind: "army", Name: army.Name, Event: ChangeAdded})
+		}
+	}
+
+	for _, actor := range before.SortedActors() {
+		if updated, found := after.Actors[actor.Name]; found {
+			changes = append(changes, diffValues("actor", actor.Name, actorDiffFields,
+				actorDiffValues(actor), actorDiffValues(updated))...)
+		}
+	}
+
+	return changes
+}
+
+// DiffElement renders a list of changes as an HTML fragment that may be dropped into a report.
+func DiffElement(fromStep, toStep int, changes []Change) *DocumentElement {
+	diffDiv := Element(Division)
+	diffDiv.Element(H3).Text = fmt.Sprintf("Changes from step %d to step %d", fromStep, toStep)
+
+	if len(changes) == 0 {
+		diffDiv.Element(HTP).Text = "Nothing changed."
+		return diffDiv
+	}
+
+	changeTable := diffDiv.Element(Table)
+	headersRow := changeTable.Element(TableHeaders).Element(TableRow)
+
+	for _, header := range []string{"Kind", "Name", "Change", "From", "To"} {
+		headersRow.Element(TableCell).Element(Span).Text = header
+	}
+
+	for _, change := range changes {
+		row := changeTable.Element(TableRow)
+		row.Element(TableCell).Text = change.Kind
+		row.Element(TableCell).Push(NameLink(change.Name))
+
+		if change.Event == ChangeUpdated {
+			row.Element(TableCell).Text = change.Field
+		} else {
+			row.Element(TableCell).Text = change.Event
+		}
+
+		row.Element(TableCell).Text = change.From
+		row.Element(TableCell).Text = change.To
+	}
+
+	return diffDiv
+}
+
+func runDiff(args []string) error {
+	flags, stateDir := newFlagSet("diff")
+	format := flags.String("format", "text", "output format: text, json or html")
+
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return err
+	} else if len(positional) != 2 {
+		return fmt.Errorf("diff expects the two steps to compare")
+	}
+
+	var steps [2]int
+	for idx, arg := range positional {
+		if steps[idx], err = strconv.Atoi(arg); err != nil {
+			return fmt.Errorf("%s is not a valid step: %v", arg, err)
+		}
+	}
+
+	simulation, err := LoadSimulationState(*stateDir)
+	if err != nil {
+		return err
+	}
+
+	var worlds [2]*World
+	for idx, step := range steps {
+		if worlds[idx], err = LoadWorld(simulation.WorldPathForStep(step)); err != nil {
+			return err
+		}
+	}
+
+	changes := DiffWorlds(worlds[0], worlds[1])
+
+	switch *format {
+	case "text":
+		fmt.Printf("Changes from step %d to step %d\n", steps[0], steps[1])
+		for _, change := range changes {
+			fmt.Printf("  %s\n", change)
+		}
+
+	case "json":
+		diff := struct {
+			From    int      `json:"from"`
+			To      int      `json:"to"`
+			Changes []Change `json:"changes"`
+		}{steps[0], steps[1], changes}
+
+		if encoded, err := json.MarshalIndent(diff, "", "  "); err != nil {
+			return err
+		} else {
+			fmt.Printf("%s\n", encoded)
+		}
+
+	case "html":
+		fmt.Print(DiffElement(steps[0], steps[1], changes))
+
+	default:
+		return fmt.Errorf("unknown format %s, expected text, json or html", *format)
+	}
+
+	return nil
+}
@@ -0,0 +1,480 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+const (
+	// Rolls whose exact distribution would take more than about this many steps to work out are
+	// estimated by rolling them distributionSamples times instead
+	maxDistributionWork = 100000000
+	distributionSamples = 20000
+	distributionSeed    = 1
+
+	// Keeping dice and multiplying rolls track totals in maps, which costs far more per step
+	// than adding rolls together
+	mapStepWork = 50
+
+	// Rolls that can produce more totals than this have no distribution at all
+	maxDistributionWidth = 1000000
+)
+
+// Distribution is the exact probability of every total a roll can produce. Probabilities[i] is
+// the chance of rolling Offset + i. Distributions of rolls too large to work out exactly are
+// estimated from Samples rolls instead, and Samples is 0 for exact distributions.
+type Distribution struct {
+	Offset        int
+	Probabilities []float64
+	Samples       int
+}
+
+// PointDistribution is a distribution that always produces the same value.
+func PointDistribution(value int) Distribution {
+	return Distribution{
+		Offset:        value,
+		Probabilities: []float64{1},
+	}
+}
+
+// distributionFromMap builds a distribution from a map of values to probabilities.
+func distributionFromMap(probabilities map[int]float64) Distribution {
+	min, max := math.MaxInt32, math.MinInt32
+	for value := range probabilities {
+		if value < min {
+			min = value
+		}
+
+		if value > max {
+			max = value
+		}
+	}
+
+	distribution := Distribution{
+		Offset:        min,
+		Probabilities: make([]float64, max-min+1),
+	}
+
+	for value, probability := range probabilities {
+		distribution.Probabilities[value-min] += probability
+	}
+
+	return distribution
+}
+
+func (s Distribution) Min() int {
+	for idx, probability := range s.Probabilities {
+		if probability > 0 {
+			return s.Offset + idx
+		}
+	}
+
+	return s.Offset
+}
+
+func (s Distribution) Max() int {
+	for idx := len(s.Probabilities) - 1; idx >= 0; idx-- {
+		if s.Probabilities[idx] > 0 {
+			return s.Offset + idx
+		}
+	}
+
+	return s.Offset
+}
+
+func (s Distribution) Probability(value int) float64 {
+	if idx := value - s.Offset; idx >= 0 && idx < len(s.Probabilities) {
+		return s.Probabilities[idx]
+	}
+
+	return 0
+}
+
+func (s Distribution) Mean() float64 {
+	mean := 0.0
+	for idx, probability := range s.Probabilities {
+		mean += float64(s.Offset+idx) * probability
+	}
+
+	return mean
+}
+
+func (s Distribution) Variance() float64 {
+	var (
+		mean     = s.Mean()
+		variance = 0.0
+	)
+
+	for idx, probability := range s.Probabilities {
+		delta := float64(s.Offset+idx) - mean
+		variance += delta * delta * probability
+	}
+
+	return variance
+}
+
+// ChanceAtLeast returns the probability of rolling the target or higher, such as the chance of
+// an attack roll meeting or beating an AC.
+func (s Distribution) ChanceAtLeast(target int) float64 {
+	chance := 0.0
+	for idx, probability := range s.Probabilities {
+		if s.Offset+idx >= target {
+			chance += probability
+		}
+	}
+
+	return chance
+}
+
+// Add returns the distribution of the sum of independent rolls from both distributions.
+func (s Distribution) Add(other Distribution) Distribution {
+	sum := Distribution{
+		Offset:        s.Offset + other.Offset,
+		Probabilities: make([]float64, len(s.Probabilities)+len(other.Probabilities)-1),
+	}
+
+	for i, left := range s.Probabilities {
+		if left == 0 {
+			continue
+		}
+
+		for j, right := range other.Probabilities {
+			sum.Probabilities[i+j] += left * right
+		}
+	}
+
+	return sum
+}
+
+// Negate returns the distribution of the negated roll.
+func (s Distribution) Negate() Distribution {
+	negated := Distribution{
+		Offset:        -(s.Offset + len(s.Probabilities) - 1),
+		Probabilities: make([]float64, len(s.Probabilities)),
+	}
+
+	for idx, probability := range s.Probabilities {
+		negated.Probabilities[len(s.Probabilities)-1-idx] = probability
+	}
+
+	return negated
+}
+
+// Multiply returns the distribution of the product of independent rolls from both distributions.
+func (s Distribution) Multiply(other Distribution) Distribution {
+	products := make(map[int]float64)
+	for i, left := range s.Probabilities {
+		if left == 0 {
+			continue
+		}
+
+		for j, right := range other.Probabilities {
+			if right > 0 {
+				products[(s.Offset+i)*(other.Offset+j)] += left * right
+			}
+		}
+	}
+
+	return distributionFromMap(products)
+}
+
+func (s *Constant) Distribution() Distribution {
+	return PointDistribution(s.Value)
+}
+
+// dieDistribution is the distribution of a single die of this term including rerolls and
+// explosions, mirroring DiceTerm.rollDie.
+func (s *DiceTerm) dieDistribution() Distribution {
+	var (
+		face      = 1 / float64(s.Sides)
+		firstFace = make(map[int]float64)
+	)
+
+	// A face at or below the reroll threshold is replaced by a second, final roll
+	for value := 1; value <= s.Sides; value++ {
+		if value > s.RerollBelow {
+			firstFace[value] += face
+		}
+
+		firstFace[value] += float64(s.RerollBelow) * face * face
+	}
+
+	if !s.Explode {
+		return distributionFromMap(firstFace)
+	}
+
+	// Each explosion rolls another die and adds it on, exploding again on the highest face
+	// until the explosion limit is reached
+	chain := PointDistribution(0)
+	for explosions := 0; explosions < maxExplosions; explosions++ {
+		next := make(map[int]float64)
+		for value := 1; value < s.Sides; value++ {
+			next[value] += face
+		}
+
+		for idx, probability := range chain.Probabilities {
+			next[s.Sides+chain.Offset+idx] += face * probability
+		}
+
+		chain = distributionFromMap(next)
+	}
+
+	die := make(map[int]float64)
+	for value, probability := range firstFace {
+		if value < s.Sides {
+			die[value] += probability
+			continue
+		}
+
+		for idx, chainProbability := range chain.Probabilities {
+			die[value+chain.Offset+idx] += probability * chainProbability
+		}
+	}
+
+	return distributionFromMap(die)
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+
+	return result
+}
+
+func (s *DiceTerm) Distribution() Distribution {
+	die := s.dieDistribution()
+
+	if s.Keep == KeepAll {
+		total := PointDistribution(0)
+		for idx := 0; idx < s.Count; idx++ {
+			total = total.Add(die)
+		}
+
+		return total
+	}
+
+	// Walk the die values from the ones we keep first (highest for keep highest) to the ones we
+	// keep last, tracking how many dice have been given a value so far and the sum of the dice
+	// that are kept. Choosing j of the remaining dice to show a value contributes
+	// C(remaining, j) * p^j to the probability.
+	type keepState struct {
+		assigned int
+		sum      int
+	}
+
+	values := make([]int, 0, len(die.Probabilities))
+	for idx := range die.Probabilities {
+		if die.Probabilities[idx] > 0 {
+			values = append(values, die.Offset+idx)
+		}
+	}
+
+	if s.Keep == KeepHighest {
+		for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+
+	states := map[keepState]float64{{}: 1}
+	for _, value := range values {
+		probability := die.Probability(value)
+		next := make(map[keepState]float64)
+
+		for state, stateProbability := range states {
+			remaining := s.Count - state.assigned
+
+			for count := 0; count <= remaining; count++ {
+				kept := s.KeepCount - state.assigned
+				if kept < 0 {
+					kept = 0
+				} else if kept > count {
+					kept = count
+				}
+
+				nextState := keepState{
+					assigned: state.assigned + count,
+					sum:      state.sum + kept*value,
+				}
+
+				next[nextState] += stateProbability * binomial(remaining, count) * math.Pow(probability, float64(count))
+			}
+		}
+
+		states = next
+	}
+
+	totals := make(map[int]float64)
+	for state, probability := range states {
+		if state.assigned == s.Count {
+			totals[state.sum] += probability
+		}
+	}
+
+	return distributionFromMap(totals)
+}
+
+func (s *BinaryExpr) Distribution() Distribution {
+	left, right := s.Left.Distribution(), s.Right.Distribution()
+
+	switch s.Operator {
+	case '+':
+		return left.Add(right)
+	case '-':
+		return left.Add(right.Negate())
+	case '*':
+		return left.Multiply(right)
+	}
+
+	panic(fmt.Sprintf("Unknown roll operator %q.", s.Operator))
+}
+
+func (s *Negation) Distribution() Distribution {
+	return s.Inner.Distribution().Negate()
+}
+
+// rollBounds is the smallest and largest total a roll can produce, along with a rough count of
+// the steps needed to work out its exact distribution.
+type rollBounds struct {
+	min  float64
+	max  float64
+	work float64
+}
+
+func (s rollBounds) width() float64 {
+	return s.max - s.min + 1
+}
+
+// add returns the bounds of the sum of both rolls.
+func (s rollBounds) add(other rollBounds) rollBounds {
+	return rollBounds{
+		min:  s.min + other.min,
+		max:  s.max + other.max,
+		work: s.work + other.work + s.width()*other.width(),
+	}
+}
+
+// boundsOf works out the bounds of an expression without working out its distribution.
+func boundsOf(expr RollExpr) rollBounds {
+	switch typed := expr.(type) {
+	case *Constant:
+		return rollBounds{min: float64(typed.Value), max: float64(typed.Value)}
+
+	case *DiceTerm:
+		var (
+			count = float64(typed.Count)
+			kept  = count
+			die   = float64(typed.Sides)
+		)
+
+		if typed.Explode {
+			die *= maxExplosions + 1
+		}
+
+		if typed.Keep == KeepAll {
+			return rollBounds{min: kept, max: kept * die, work: count * count * die * die / 2}
+		}
+
+		kept = float64(typed.KeepCount)
+		return rollBounds{min: kept, max: kept * die, work: count * die * kept * kept * die * mapStepWork}
+
+	case *BinaryExpr:
+		left, right := boundsOf(typed.Left), boundsOf(typed.Right)
+
+		switch typed.Operator {
+		case '+':
+			return left.add(right)
+		case '-':
+			return left.add(boundsOf(&Negation{Inner: typed.Right}))
+		case '*':
+			products := []float64{left.min * right.min, left.min * right.max, left.max * right.min, left.max * right.max}
+			return rollBounds{
+				min:  math.Min(math.Min(products[0], products[1]), math.Min(products[2], products[3])),
+				max:  math.Max(math.Max(products[0], products[1]), math.Max(products[2], products[3])),
+				work: left.work + right.work + left.width()*right.width()*mapStepWork,
+			}
+		}
+
+	case *Negation:
+		inner := boundsOf(typed.Inner)
+		return rollBounds{min: -inner.max, max: -inner.min, work: inner.work}
+	}
+
+	return rollBounds{}
+}
+
+// parseBounds parses every die in the spec and works out the bounds of their sum. An error is
+// returned if a die is invalid or the roll can produce too many totals to have a distribution.
+func (s RollSpec) parseBounds() (rollBounds, []RollExpr, error) {
+	var (
+		bounds rollBounds
+		exprs  []RollExpr
+	)
+
+	for _, die := range s {
+		if expr, err := die.Parse(); err != nil {
+			return rollBounds{}, nil, err
+		} else {
+			bounds = bounds.add(boundsOf(expr))
+			exprs = append(exprs, expr)
+		}
+	}
+
+	if bounds.width() > maxDistributionWidth {
+		return rollBounds{}, nil, fmt.Errorf("%s can produce over %d different totals, too many to work out its odds", s, maxDistributionWidth)
+	}
+
+	return bounds, exprs, nil
+}
+
+// HitChance returns the chance of an attack roll meeting or beating the given AC, and whether that
+// chance is only an estimate.
+func (s RollSpec) HitChance(ac int) (float64, bool, error) {
+	if distribution, err := s.Distribution(); err != nil {
+		return 0, false, err
+	} else {
+		return distribution.ChanceAtLeast(ac), distribution.Samples > 0, nil
+	}
+}
+
+// Distribution computes the exact distribution of the sum of every die in the spec, or estimates
+// it for rolls too large to work out exactly.
+func (s RollSpec) Distribution() (Distribution, error) {
+	bounds, exprs, err := s.parseBounds()
+	if err != nil {
+		return Distribution{}, err
+	} else if bounds.work > maxDistributionWork {
+		return s.sampleDistribution()
+	}
+
+	total := PointDistribution(0)
+	for _, expr := range exprs {
+		total = total.Add(expr.Distribution())
+	}
+
+	return total, nil
+}
+
+// sampleDistribution estimates the distribution of the spec by rolling it many times. The rolls
+// always start from the same seed so that the estimate doesn't change between reports.
+func (s RollSpec) sampleDistribution() (Distribution, error) {
+	var (
+		rng    = rand.New(rand.NewSource(distributionSeed))
+		totals = make(map[int]float64)
+	)
+
+	for idx := 0; idx < distributionSamples; idx++ {
+		if record, err := s.Roll(rng); err != nil {
+			return Distribution{}, err
+		} else {
+			totals[record.Total] += 1.0 / distributionSamples
+		}
+	}
+
+	distribution := distributionFromMap(totals)
+	distribution.Samples = distributionSamples
+
+	return distribution, nil
+}
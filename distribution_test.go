@@ -0,0 +1,85 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistribution(t *testing.T) {
+	tests := []struct {
+		roll   string
+		mean   float64
+		ac     int
+		chance float64
+	}{
+		{roll: "d20+6", mean: 16.5, ac: 19, chance: 0.40},
+		{roll: "4d6kh3", mean: 12.245, ac: 18, chance: 21.0 / 1296},
+		{roll: "2d6", mean: 7, ac: 7, chance: 21.0 / 36},
+		{roll: "2d20kh1", mean: 13.825, ac: 11, chance: 0.75},
+		{roll: "2d20kl1", mean: 7.175, ac: 11, chance: 0.25},
+		{roll: "d6r1", mean: 23.5 / 6, ac: 2, chance: 35.0 / 36},
+		{roll: "d4-1", mean: 1.5, ac: 0, chance: 1},
+		{roll: "d6*2", mean: 7, ac: 12, chance: 1.0 / 6},
+	}
+
+	for _, test := range tests {
+		distribution, err := RollSpec{Die(test.roll)}.Distribution()
+		if err != nil {
+			t.Errorf("%s: %v", test.roll, err)
+			continue
+		}
+
+		if distribution.Samples != 0 {
+			t.Errorf("%s was estimated from %d rolls, expected an exact distribution", test.roll, distribution.Samples)
+		}
+
+		if mean := distribution.Mean(); math.Abs(mean-test.mean) > 0.001 {
+			t.Errorf("%s has a mean of %.4f, expected %.4f", test.roll, mean, test.mean)
+		}
+
+		if chance := distribution.ChanceAtLeast(test.ac); math.Abs(chance-test.chance) > 1e-9 {
+			t.Errorf("%s meets or beats %d %.4f of the time, expected %.4f", test.roll, test.ac, chance, test.chance)
+		}
+	}
+}
+
+func TestDistributionOfLargeRolls(t *testing.T) {
+	tests := []struct {
+		roll string
+		mean float64
+	}{
+		{roll: "100d1000", mean: 50050},
+		{roll: "100d1000kh50", mean: 37376},
+	}
+
+	for _, test := range tests {
+		distribution, err := RollSpec{Die(test.roll)}.Distribution()
+		if err != nil {
+			t.Errorf("%s: %v", test.roll, err)
+		} else if distribution.Samples == 0 {
+			t.Errorf("%s was worked out exactly, expected an estimate", test.roll)
+		} else if mean := distribution.Mean(); math.Abs(mean-test.mean)/test.mean > 0.01 {
+			t.Errorf("%s has an estimated mean of %.1f, expected about %.1f", test.roll, mean, test.mean)
+		}
+	}
+
+	if _, err := (RollSpec{Die("100d1000*100d1000")}).Distribution(); err == nil {
+		t.Errorf("100d1000*100d1000 has a distribution, expected it to be too wide")
+	}
+}
+
+func TestHitChanceMarksEstimates(t *testing.T) {
+	world := NewWorld()
+
+	if chance, estimated, err := world.HitChance(RollSpec{Die("d20+6")}, 19); err != nil {
+		t.Errorf("d20+6: %v", err)
+	} else if estimated || math.Abs(chance-0.40) > 1e-9 {
+		t.Errorf("d20+6 hits AC 19 %.4f of the time (estimated %t), expected exactly 0.40", chance, estimated)
+	}
+
+	if _, estimated, err := world.HitChance(RollSpec{Die("100d1000")}, 50000); err != nil {
+		t.Errorf("100d1000: %v", err)
+	} else if !estimated {
+		t.Errorf("100d1000 hit chance was not marked as an estimate")
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"fmt"
+)
+
+// EconomyRules sets how much settlements produce each turn for every thousand people living
+// there and what armies cost to keep in the field. Occupied settlements only produce
+// OccupiedPercent of their usual output. Each army costs a gold and a food every turn for each
+// UpkeepHP of its maximum HP, and when an actor can't pay in full every one of its armies loses
+// AttritionPercent of its maximum HP to desertion.
+type EconomyRules struct {
+	GoldPerThousand      int
+	FoodPerThousand      int
+	MaterialsPerThousand int
+	OccupiedPercent      int
+	UpkeepHP             int
+	AttritionPercent     int
+}
+
+var defaultEconomy = EconomyRules{
+	GoldPerThousand:      2,
+	FoodPerThousand:      4,
+	MaterialsPerThousand: 1,
+	OccupiedPercent:      50,
+	UpkeepHP:             10,
+	AttritionPercent:     10,
+}
+
+// Economy returns the economy rules for this world.
+func (s *World) Economy() *EconomyRules {
+	if s.Rules == nil || s.Rules.Economy == nil {
+		return &defaultEconomy
+	}
+
+	return s.Rules.Economy
+}
+
+// Resources is an amount of each of the resources an actor holds.
+type Resources struct {
+	Gold      int
+	Food      int
+	Materials int
+}
+
+func (s Resources) String() string {
+	return printer.Sprintf("%d gold, %d food, %d materials", s.Gold, s.Food, s.Materials)
+}
+
+// Production returns what the settlement produces in a turn.
+func (s *EconomyRules) Production(settlement *Settlement) Resources {
+	thousands := int(settlement.Population / 1000)
+
+	production := Resources{
+		Gold:      thousands * s.GoldPerThousand,
+		Food:      thousands * s.FoodPerThousand,
+		Materials: thousands * s.MaterialsPerThousand,
+	}
+
+	if settlement.Occupied {
+		production.Gold = production.Gold * s.OccupiedPercent / 100
+		production.Food = production.Food * s.OccupiedPercent / 100
+		production.Materials = production.Materials * s.OccupiedPercent / 100
+	}
+
+	return production
+}
+
+// Upkeep returns what the army costs to keep in the field for a turn.
+func (s *EconomyRules) Upkeep(army *Army) Resources {
+	if army.Destroyed || s.UpkeepHP <= 0 {
+		return Resources{}
+	}
+
+	cost := army.HP.Max / s.UpkeepHP
+	if cost < 1 {
+		cost = 1
+	}
+
+	return Resources{
+		Gold: cost,
+		Food: cost,
+	}
+}
+
+// Holdings returns the resources the actor holds.
+func (s *WorldActor) Holdings() Resources {
+	return Resources{
+		Gold:      s.Treasury,
+		Food:      s.Food,
+		Materials: s.Materials,
+	}
+}
+
+// Afford returns true if the actor holds at least the given resources.
+func (s *WorldActor) Afford(cost Resources) bool {
+	return s.Treasury >= cost.Gold && s.Food >= cost.Food && s.Materials >= cost.Materials
+}
+
+// Spend takes the given resources from the actor, stopping at nothing. Returns true if the
+// actor could pay in full.
+func (s *WorldActor) Spend(cost Resources) bool {
+	paid := s.Afford(cost)
+
+	s.Treasury = spend(s.Treasury, cost.Gold)
+	s.Food = spend(s.Food, cost.Food)
+	s.Materials = spend(s.Materials, cost.Materials)
+
+	return paid
+}
+
+func spend(held, amount int) int {
+	if held < amount {
+		return 0
+	}
+
+	return held - amount
+}
+
+// Earn adds the given resources to what the actor holds.
+func (s *WorldActor) Earn(income Resources) {
+	s.Treasury += income.Gold
+	s.Food += income.Food
+	s.Materials += income.Materials
+}
+
+// stepEconomy collects what each actor's settlements produce and pays its armies' upkeep,
+// reporting each actor's economy for the turn. Armies whose actor can't pay their upkeep lose
+// HP as soldiers desert.
+func (s *World) stepEconomy(log *DocumentElement) bool {
+	var (
+		activityObserved   = false
+		economy            = s.Economy()
+		settlementsByActor = s.SettlementsByActor()
+		armiesByActor      = s.ArmiesByActor()
+	)
+
+	log.Element(H3).Text = "Economy"
+
+	for _, actor := range s.SortedActors() {
+		var income, upkeep Resources
+
+		for _, settlement := range settlementsByActor[actor.Name] {
+			// Nothing gets in or out of a settlement under siege
+			if len(s.Besiegers(settlement)) > 0 {
+				continue
+			}
+
+			production := economy.Production(settlement)
+
+			income.Gold += production.Gold
+			income.Food += production.Food
+			income.Materials += production.Materials
+		}
+
+		for _, army := range armiesByActor[actor.Name] {
+			cost := economy.Upkeep(army)
+
+			upkeep.Gold += cost.Gold
+			upkeep.Food += cost.Food
+		}
+
+		actor.Earn(income)
+		paid := actor.Spend(upkeep)
+
+		log.Element(H4).Text = actor.Name
+
+		economyTable := log.Element(Table)
+		economyTable.Attributes["style"] = "margin-left: 20px;"
+
+		for _, line := range []struct {
+			Name      string
+			Resources Resources
+		}{
+			{"Income", income},
+			{"Upkeep", upkeep},
+			{"Holdings", actor.Holdings()},
+		} {
+			row := economyTable.Element(TableRow)
+
+			fieldName := row.Element(TableCell).Element(Span)
+			fieldName.Attributes["style"] = "font-weight: bold;"
+			fieldName.Text = line.Name
+
+			row.Element(TableCell).Element(Span).Text = line.Resources.String()
+		}
+
+		if paid {
+			continue
+		}
+
+		activityObserved = true
+
+		attritionList := log.Element(UnorderedList)
+		attritionList.Attributes["style"] = "list-style-type: none;"
+		attritionList.Element(ListItem).Text = fmt.Sprintf("%s can't pay its armies' upkeep in full.", actor.Name)
+
+		for _, army := range armiesByActor[actor.Name].Sorted() {
+			if army.Destroyed {
+				continue
+			}
+
+			attrition := army.HP.Max * economy.AttritionPercent / 100
+			if attrition < 1 {
+				attrition = 1
+			}
+
+			if army.Damage(attrition); army.Destroyed {
+				attritionList.Element(ListItem).Text = fmt.Sprintf("Army %s has deserted!", NameLink(army.Name))
+			} else {
+				attritionList.Element(ListItem).Text = fmt.Sprintf("Army %s loses %d HP to desertion.", NameLink(army.Name), attrition)
+			}
+		}
+	}
+
+	return activityObserved
+}
@@ -0,0 +1,84 @@
+package main
+
+import "testing"
+
+func TestProductionAndUpkeep(t *testing.T) {
+	settlement := &Settlement{Name: "Wroat", Population: 5400}
+
+	if production := defaultEconomy.Production(settlement); production != (Resources{Gold: 10, Food: 20, Materials: 5}) {
+		t.Errorf("settlement of 5,400 produces %s", production)
+	}
+
+	settlement.Occupied = true
+	if production := defaultEconomy.Production(settlement); production != (Resources{Gold: 5, Food: 10, Materials: 2}) {
+		t.Errorf("occupied settlement of 5,400 produces %s", production)
+	}
+
+	tests := []struct {
+		maxHP     int
+		destroyed bool
+		upkeep    int
+	}{
+		{maxHP: 50, upkeep: 5},
+		{maxHP: 59, upkeep: 5},
+		{maxHP: 4, upkeep: 1},
+		{maxHP: 50, destroyed: true, upkeep: 0},
+	}
+
+	for _, test := range tests {
+		army := &Army{HP: &HealthTracker{Current: test.maxHP, Max: test.maxHP}, Destroyed: test.destroyed}
+		if upkeep := defaultEconomy.Upkeep(army); upkeep != (Resources{Gold: test.upkeep, Food: test.upkeep}) {
+			t.Errorf("army of %d HP (destroyed %t) costs %s, expected %d gold and food", test.maxHP, test.destroyed, upkeep, test.upkeep)
+		}
+	}
+}
+
+func TestStepEconomy(t *testing.T) {
+	var (
+		world   = newTestWorld()
+		aundair = world.Actors["Aundair"]
+		thrane  = world.Actors["Thrane"]
+	)
+
+	world.stepEconomy(Element(Division))
+
+	// Three settlements of a thousand people, less the upkeep of a 50 HP army
+	if holdings := aundair.Holdings(); holdings != (Resources{Gold: 101, Food: 107, Materials: 3}) {
+		t.Errorf("Aundair holds %s after a turn", holdings)
+	}
+
+	if holdings := thrane.Holdings(); holdings != (Resources{Gold: 102, Food: 104, Materials: 1}) {
+		t.Errorf("Thrane holds %s after a turn", holdings)
+	}
+}
+
+func TestStepEconomyAttrition(t *testing.T) {
+	var (
+		world = newTestWorld()
+		army  = world.Armies["First Host"]
+	)
+
+	for _, settlement := range world.Settlements {
+		settlement.Population = 0
+	}
+
+	world.Actors["Aundair"].Treasury = 2
+
+	if !world.stepEconomy(Element(Division)) {
+		t.Errorf("unpaid upkeep was not reported")
+	} else if army.HP.Current != 45 {
+		t.Errorf("unpaid army has %d HP, expected a tenth of it to desert", army.HP.Current)
+	}
+
+	if holdings := world.Actors["Aundair"].Holdings(); holdings.Gold != 0 || holdings.Food != 95 {
+		t.Errorf("Aundair holds %s after paying what it could", holdings)
+	}
+}
+
+func TestSiegeStopsProduction(t *testing.T) {
+	world := newTestSiege(StanceSiege)
+
+	if world.stepEconomy(Element(Division)); world.Actors["Thrane"].Holdings() != (Resources{Gold: 100, Food: 100}) {
+		t.Errorf("Thrane holds %s, expected besieged Olath to produce nothing", world.Actors["Thrane"].Holdings())
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	fortificationHPPerDefense = 10
+	minimumFortificationHP    = 10
+	repairFraction            = 10
+)
+
+// FortificationRule says how fortifications of one type combine with the rest of a
+// settlement's fortifications. Only the best fortification of a type counts unless the type
+// stacks. A type that requires another only counts when the settlement has a fortification of
+// that type too. Inner fortifications don't count while an outer wall is standing, since
+// attackers have to breach the outer wall before they can reach them. Types that take damage
+// soak up part of every successful attack on the settlement until they are breached.
+type FortificationRule struct {
+	Stacks      bool
+	Requires    []FortificationType
+	Inner       bool
+	Outer       bool
+	TakesDamage bool
+}
+
+var FortificationRules = map[FortificationType]FortificationRule{
+	Wall: {
+		Inner:       true,
+		TakesDamage: true,
+	},
+	WallAddon: {
+		Requires: []FortificationType{Wall},
+		Inner:    true,
+	},
+	Garrison: {
+		Stacks: true,
+	},
+	OuterWall: {
+		Outer:       true,
+		TakesDamage: true,
+	},
+}
+
+func (s FortificationType) String() string {
+	switch s {
+	case Wall:
+		return "Wall"
+	case WallAddon:
+		return "Wall Addon"
+	case Garrison:
+		return "Garrison"
+	case OuterWall:
+		return "Outer Wall"
+	}
+
+	return fmt.Sprintf("Unknown (%d)", uint(s))
+}
+
+// FortificationEffect is whether a settlement's fortification counts toward its AC and attack
+// rolls, and why not if it doesn't.
+type FortificationEffect struct {
+	Fortification *Fortification
+	Counted       bool
+	Reason        string
+}
+
+// OuterWallStanding returns true if the settlement has an outer wall that hasn't been breached.
+func (s *Settlement) OuterWallStanding() bool {
+	for _, fortification := range s.Fortifications {
+		if FortificationRules[fortification.Type].Outer && !fortification.Breached {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FortificationEffects works out which of the settlement's fortifications count under the
+// fortification rules.
+func (s *Settlement) FortificationEffects() []FortificationEffect {
+	var (
+		effects       []FortificationEffect
+		best          = make(map[FortificationType]int)
+		present       = make(map[FortificationType]bool)
+		outerStanding = s.OuterWallStanding()
+	)
+
+	for idx, fortification := range s.Fortifications {
+		if fortification.Breached {
+			continue
+		}
+
+		present[fortification.Type] = true
+
+		if bestIdx, found := best[fortification.Type]; !found || fortification.DefenseModifier > s.Fortifications[bestIdx].DefenseModifier {
+			best[fortification.Type] = idx
+		}
+	}
+
+	for idx := range s.Fortifications {
+		var (
+			fortification = &s.Fortifications[idx]
+			effect        = FortificationEffect{Fortification: fortification}
+			rule, known   = FortificationRules[fortification.Type]
+		)
+
+		switch {
+		case !known:
+			effect.Reason = "unknown fortification type"
+		case fortification.Breached:
+			effect.Reason = "breached"
+		case rule.Inner && outerStanding:
+			effect.Reason = "behind an outer wall that hasn't been breached"
+		case !rule.Stacks && best[fortification.Type] != idx:
+			effect.Reason = fmt.Sprintf("only the best %s counts", strings.ToLower(fortification.Type.String()))
+		default:
+			effect.Counted = true
+		}
+
+		for _, required := range rule.Requires {
+			if effect.Counted && !present[required] {
+				effect.Counted = false
+				effect.Reason = fmt.Sprintf("needs a %s", strings.ToLower(required.String()))
+			}
+		}
+
+		effects = append(effects, effect)
+	}
+
+	return effects
+}
+
+func (s *Settlement) AC() int {
+	settlementAC := 0
+	for _, effect := range s.FortificationEffects() {
+		if effect.Counted {
+			settlementAC += effect.Fortification.DefenseModifier
+		}
+	}
+
+	return settlementAC
+}
+
+func (s *Settlement) attackModifier() int {
+	attackModifier := 0
+	for _, effect := range s.FortificationEffects() {
+		if effect.Counted {
+			attackModifier += effect.Fortification.AttackModifier
+		}
+	}
+
+	return attackModifier
+}
+
+// ACBreakdown describes how the settlement's AC is made up from the fortifications that count.
+func (s *Settlement) ACBreakdown() string {
+	var parts []string
+	for _, effect := range s.FortificationEffects() {
+		if effect.Counted {
+			parts = append(parts, fmt.Sprintf("%s %+d", effect.Fortification.Name, effect.Fortification.DefenseModifier))
+		}
+	}
+
+	if len(parts) == 0 {
+		return "No fortifications"
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// DefaultFortificationHP is the HP a fortification is given when none is set, which is
+// more for stronger fortifications.
+func DefaultFortificationHP(defenseModifier int) int {
+	if hp := defenseModifier * fortificationHPPerDefense; hp > minimumFortificationHP {
+		return hp
+	}
+
+	return minimumFortificationHP
+}
+
+// siegeTarget returns the fortification that takes the brunt of attacks on the settlement,
+// which is a standing outer wall if there is one and otherwise the wall that counts. Returns
+// nil if nothing stands between attackers and the settlement.
+func (s *Settlement) siegeTarget() *Fortification {
+	var target *Fortification
+
+	for _, effect := range s.FortificationEffects() {
+		rule := FortificationRules[effect.Fortification.Type]
+		if !effect.Counted || !rule.TakesDamage || effect.Fortification.HP == nil {
+			continue
+		}
+
+		if rule.Outer {
+			return effect.Fortification
+		} else if target == nil {
+			target = effect.Fortification
+		}
+	}
+
+	return target
+}
+
+// DamageFortifications splits the damage from a successful attack between the settlement's
+// fortifications and the settlement itself. A standing outer wall shields the settlement from
+// all of it, while a wall takes half, or all of it from an army with siege engines. Damage
+// beyond what breaches a fortification carries through to the settlement. Returns the damage
+// left for the settlement and the fortification that was breached, if any.
+func (s *Settlement) DamageFortifications(damage int, siegeEngines bool) (int, *Fortification) {
+	target := s.siegeTarget()
+	if target == nil || damage <= 0 {
+		return damage, nil
+	}
+
+	share := damage / 2
+	if siegeEngines || FortificationRules[target.Type].Outer {
+		share = damage
+	}
+
+	remaining := damage - share
+	if share > target.HP.Current {
+		remaining += share - target.HP.Current
+	}
+
+	if target.HP.Damage(share); target.HP.Current <= 0 {
+		target.Breached = true
+		return remaining, target
+	}
+
+	return remaining, nil
+}
+
+// Damaged returns true if the fortification has been breached or has lost HP.
+func (s *Fortification) Damaged() bool {
+	return s.Breached || (s.HP != nil && s.HP.Current < s.HP.Max)
+}
+
+// repair restores some of a fortification's HP, closing the breach once it is whole again.
+// Returns true if the fortification has been fully repaired.
+func (s *Fortification) repair() bool {
+	if s.HP == nil {
+		s.Breached = false
+		return true
+	}
+
+	amount := s.HP.Max / repairFraction
+	if amount < 1 {
+		amount = 1
+	}
+
+	if s.HP.Current += amount; s.HP.Current >= s.HP.Max {
+		s.HP.Current = s.HP.Max
+		s.Breached = false
+	}
+
+	return !s.Damaged()
+}
+
+// repairFortifications works on the fortifications of every settlement that has been ordered to
+// repair them. Repairs can't go on while enemy armies are in the settlement.
+func (s *World) repairFortifications(log *DocumentElement) bool {
+	var (
+		activityObserved = false
+		repairList       = log.Element(UnorderedList)
+	)
+
+	repairList.Attributes["style"] = "list-style-type: none;"
+
+	for _, settlement := range SettlementListFromMap(s.Settlements).Sorted() {
+		if !settlement.Repairing {
+			continue
+		}
+
+		if enemies := s.EnemiesAt(settlement.Name, settlement.Allegiance); len(enemies) > 0 {
+			repairList.Element(ListItem).Text = fmt.Sprintf("Repairs at %s are halted while army %s is present.",
+				NameLink(settlement.Name), NameLink(enemies[0].Name))
+			continue
+		}
+
+		activityObserved = true
+		settlement.Repairing = false
+
+		for idx := range settlement.Fortifications {
+			fortification := &settlement.Fortifications[idx]
+			if !fortification.Damaged() {
+				continue
+			}
+
+			wasBreached := fortification.Breached
+			if fortification.repair() {
+				if wasBreached {
+					repairList.Element(ListItem).Text = fmt.Sprintf("%s has closed the breach in its %s.",
+						NameLink(settlement.Name), fortification.Name)
+				} else {
+					repairList.Element(ListItem).Text = fmt.Sprintf("%s has finished repairing its %s.",
+						NameLink(settlement.Name), fortification.Name)
+				}
+			} else {
+				settlement.Repairing = true
+				repairList.Element(ListItem).Text = fmt.Sprintf("%s repairs its %s (%d / %d HP).",
+					NameLink(settlement.Name), fortification.Name, fortification.HP.Current, fortification.HP.Max)
+			}
+		}
+	}
+
+	return activityObserved
+}
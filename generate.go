@@ -0,0 +1,377 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+var (
+	generatedActorNames = []string{
+		"Aundair", "Breland", "Karrnath", "Thrane", "Cyre", "Zilargo", "Darguun", "Valenar",
+	}
+
+	generatedNamePrefixes = []string{
+		"Ash", "Bel", "Cor", "Dun", "Ell", "Fen", "Gal", "Har", "Ir", "Kel", "Lor",
+		"Mar", "Nor", "Os", "Pel", "Rav", "Sil", "Tor", "Ul", "Val", "Wyn",
+	}
+
+	generatedNameSuffixes = []string{
+		"ford", "haven", "mere", "keep", "wick", "gard", "holm", "crest", "dale", "moor",
+		"stead", "wall", "ton", "bury",
+	}
+
+	ordinals = []string{
+		"First", "Second", "Third", "Fourth", "Fifth", "Sixth", "Seventh", "Eighth", "Ninth", "Tenth",
+	}
+
+	// The fortifications a generated settlement may have, from the walls every settlement starts
+	// with to the extras that are added depending on the fortification density
+	generatedFortifications = map[string]Fortification{
+		"Wooden Walls":       {Type: Wall, DefenseModifier: 10, AttackModifier: 1},
+		"Stone Walls":        {Type: Wall, DefenseModifier: 15, AttackModifier: 3},
+		"Wooden Battlements": {Type: WallAddon, DefenseModifier: 4, AttackModifier: 1},
+		"Stone Battlements":  {Type: WallAddon, DefenseModifier: 7, AttackModifier: 2},
+		"Barracks":           {Type: Garrison, DefenseModifier: 2, AttackModifier: 2},
+		"Wooden Outer Walls": {Type: OuterWall, DefenseModifier: 10, AttackModifier: 1},
+	}
+
+	generatedSettlementArchetypes = map[string]SettlementArchetype{
+		"Village": {
+			PopulationPerHP: 100,
+			DamageRoll:      RollSpec{"d4"},
+			Fortifications:  []string{"Wooden Walls"},
+		},
+		"Town": {
+			PopulationPerHP: 100,
+			DamageRoll:      RollSpec{"d4"},
+			HasWarGuard:     true,
+			Fortifications:  []string{"Wooden Walls", "Wooden Battlements"},
+		},
+		"City": {
+			PopulationPerHP: 100,
+			DamageRoll:      RollSpec{"d6"},
+			HasWarGuard:     true,
+			Fortifications:  []string{"Stone Walls", "Stone Battlements"},
+		},
+	}
+
+	generatedArmyArchetypes = map[string]ArmyArchetype{
+		"Levy": {
+			HP:         40,
+			AC:         17,
+			AttackRoll: RollSpec{"d20+2"},
+			DamageRoll: RollSpec{"d6"},
+		},
+		"Regiment": {
+			HP:         60,
+			AC:         19,
+			AttackRoll: RollSpec{"d20+4"},
+			DamageRoll: RollSpec{"d8"},
+		},
+		"Knights": {
+			HP:         45,
+			AC:         22,
+			AttackRoll: RollSpec{"d20+6"},
+			DamageRoll: RollSpec{"d8+2"},
+		},
+	}
+
+	// What each army archetype costs out of an actor's army budget, cheapest first
+	generatedArmyCosts = []struct {
+		Archetype string
+		Cost      int
+	}{
+		{"Levy", 60},
+		{"Regiment", 100},
+		{"Knights", 140},
+	}
+)
+
+// GeneratorOptions controls the size and balance of a generated world. FortificationDensity is
+// the chance, from 0 to 1, of a settlement getting each fortification beyond its basic walls.
+// ArmyBudget is what each actor may spend on its starting armies, and each actor starts with as
+// much again in its treasury.
+type GeneratorOptions struct {
+	Seed                 int64
+	Actors               int
+	Settlements          int
+	FortificationDensity float64
+	ArmyBudget           int
+}
+
+type generatedSite struct {
+	Settlement *ScenarioSettlement
+	X, Y       float64
+}
+
+func (s *generatedSite) distance(other *generatedSite) float64 {
+	return math.Hypot(s.X-other.X, s.Y-other.Y)
+}
+
+// generatedNames makes count unique settlement names from the name parts.
+func generatedNames(rng *rand.Rand, count int) []string {
+	var names []string
+	for _, combination := range rng.Perm(len(generatedNamePrefixes) * len(generatedNameSuffixes)) {
+		prefix := generatedNamePrefixes[combination/len(generatedNameSuffixes)]
+		names = append(names, prefix+generatedNameSuffixes[combination%len(generatedNameSuffixes)])
+	}
+
+	// Reuse the names with a numeral once every combination has been used
+	for idx := len(names); idx < count; idx++ {
+		names = append(names, fmt.Sprintf("%s %d", names[idx%len(names)], idx/len(names)+1))
+	}
+
+	return names[:count]
+}
+
+// ordinal names the position idx counting from zero, such as "First" or "21st".
+func ordinal(idx int) string {
+	if idx < len(ordinals) {
+		return ordinals[idx]
+	}
+
+	number := idx + 1
+	if teen := number % 100; teen >= 11 && teen <= 13 {
+		return fmt.Sprintf("%dth", number)
+	}
+
+	switch number % 10 {
+	case 1:
+		return fmt.Sprintf("%dst", number)
+	case 2:
+		return fmt.Sprintf("%dnd", number)
+	case 3:
+		return fmt.Sprintf("%drd", number)
+	}
+
+	return fmt.Sprintf("%dth", number)
+}
+
+// fortify picks the fortifications for a settlement, starting from its archetype's and adding
+// more depending on the fortification density.
+func fortify(rng *rand.Rand, archetype string, density float64) []string {
+	fortifications := append([]string(nil), generatedSettlementArchetypes[archetype].Fortifications...)
+
+	if fortifications[0] == "Wooden Walls" && rng.Float64() < density {
+		fortifications[0] = "Stone Walls"
+	}
+
+	if len(fortifications) == 1 && rng.Float64() < density {
+		if fortifications[0] == "Stone Walls" {
+			fortifications = append(fortifications, "Stone Battlements")
+		} else {
+			fortifications = append(fortifications, "Wooden Battlements")
+		}
+	}
+
+	if rng.Float64() < density/2 {
+		fortifications = append(fortifications, "Barracks")
+	}
+
+	if rng.Float64() < density/4 {
+		fortifications = append(fortifications, "Wooden Outer Walls")
+	}
+
+	return fortifications
+}
+
+// connect builds a road network that links every site, using the shortest roads that join the
+// network together and then some extra roads so that there is more than one way to march.
+func connect(rng *rand.Rand, sites []*generatedSite) []Route {
+	var (
+		routes    []Route
+		connected = map[int]bool{0: true}
+		linked    = make(map[[2]int]bool)
+	)
+
+	roadKey := func(from, to int) [2]int {
+		if from > to {
+			return [2]int{to, from}
+		}
+
+		return [2]int{from, to}
+	}
+
+	road := func(from, to int) {
+		linked[roadKey(from, to)] = true
+		routes = append(routes, Route{
+			From:     sites[from].Settlement.Name,
+			To:       sites[to].Settlement.Name,
+			Distance: int(math.Max(1, math.Round(sites[from].distance(sites[to])*3))),
+		})
+	}
+
+	// Join the closest unconnected site to the network until every site is connected
+	for len(connected) < len(sites) {
+		best, bestFrom, bestDistance := -1, -1, math.Inf(1)
+
+		for from := range sites {
+			if !connected[from] {
+				continue
+			}
+
+			for to := range sites {
+				if distance := sites[from].distance(sites[to]); !connected[to] && distance < bestDistance {
+					best, bestFrom, bestDistance = to, from, distance
+				}
+			}
+		}
+
+		connected[best] = true
+		road(bestFrom, best)
+	}
+
+	// Give some sites a second road to their next nearest neighbour
+	for from := range sites {
+		if rng.Float64() >= 0.4 {
+			continue
+		}
+
+		neighbours := rng.Perm(len(sites))
+		sort.SliceStable(neighbours, func(i, j int) bool {
+			return sites[from].distance(sites[neighbours[i]]) < sites[from].distance(sites[neighbours[j]])
+		})
+
+		for _, to := range neighbours {
+			if to != from && !linked[roadKey(from, to)] {
+				road(from, to)
+				break
+			}
+		}
+	}
+
+	return routes
+}
+
+// GenerateScenario creates a random scenario that gives every actor the same share of
+// settlements and the same army budget. The same options always generate the same scenario.
+func GenerateScenario(options GeneratorOptions) (*Scenario, error) {
+	if options.Actors < 2 {
+		return nil, fmt.Errorf("a generated world needs at least 2 actors")
+	} else if options.Settlements < options.Actors {
+		return nil, fmt.Errorf("a generated world needs at least one settlement per actor")
+	} else if options.FortificationDensity < 0 || options.FortificationDensity > 1 {
+		return nil, fmt.Errorf("fortification density must be between 0 and 1")
+	} else if options.ArmyBudget < generatedArmyCosts[0].Cost {
+		return nil, fmt.Errorf("an army budget of %d can't buy any armies, the cheapest costs %d", options.ArmyBudget, generatedArmyCosts[0].Cost)
+	}
+
+	rng := rand.New(rand.NewSource(options.Seed))
+
+	scenario := &Scenario{
+		Name:                 fmt.Sprintf("Generated %d", options.Seed),
+		Fortifications:       generatedFortifications,
+		SettlementArchetypes: generatedSettlementArchetypes,
+		ArmyArchetypes:       generatedArmyArchetypes,
+		Actors:               make(map[string]*ScenarioActor),
+	}
+
+	var actorNames []string
+	for idx := 0; idx < options.Actors; idx++ {
+		if idx < len(generatedActorNames) {
+			actorNames = append(actorNames, generatedActorNames[idx])
+		} else {
+			actorNames = append(actorNames, fmt.Sprintf("Actor %d", idx+1))
+		}
+
+		scenario.Actors[actorNames[idx]] = &ScenarioActor{
+			Placement: PlacementSpread,
+			Treasury:  options.ArmyBudget,
+		}
+	}
+
+	// Populations are dealt out largest first in a snake order so that every actor's
+	// settlements add up to about the same
+	populations := make([]uint, options.Settlements)
+	for idx := range populations {
+		populations[idx] = uint(1000 * math.Round(math.Exp(rng.Float64()*math.Log(30))))
+	}
+
+	sort.Slice(populations, func(i, j int) bool {
+		return populations[i] > populations[j]
+	})
+
+	var (
+		names = generatedNames(rng, options.Settlements)
+		sites []*generatedSite
+	)
+
+	for idx, population := range populations {
+		var (
+			round = idx / options.Actors
+			actor = idx % options.Actors
+		)
+
+		if round%2 == 1 {
+			actor = options.Actors - 1 - actor
+		}
+
+		archetype := "Village"
+		if population >= 10000 {
+			archetype = "City"
+		} else if population >= 3000 {
+			archetype = "Town"
+		}
+
+		scenario.Settlements = append(scenario.Settlements, ScenarioSettlement{
+			Name:           names[idx],
+			Archetype:      archetype,
+			Allegiance:     actorNames[actor],
+			Population:     population,
+			Fortifications: fortify(rng, archetype, options.FortificationDensity),
+		})
+
+		// Each actor's lands surround its largest settlement, with the actors spread around a
+		// circle
+		angle := 2 * math.Pi * float64(actor) / float64(options.Actors)
+		site := &generatedSite{
+			X: math.Cos(angle),
+			Y: math.Sin(angle),
+		}
+
+		if round > 0 {
+			offsetAngle, offset := rng.Float64()*2*math.Pi, 0.2+rng.Float64()*0.6
+			site.X += math.Cos(offsetAngle) * offset
+			site.Y += math.Sin(offsetAngle) * offset
+		}
+
+		sites = append(sites, site)
+	}
+
+	for idx, site := range sites {
+		site.Settlement = &scenario.Settlements[idx]
+	}
+
+	scenario.Routes = connect(rng, sites)
+
+	// Every actor spends the same budget on armies, picking what to buy at random from what it
+	// can still afford
+	for _, actor := range actorNames {
+		var (
+			budget = options.ArmyBudget
+			bought = make(map[string]int)
+		)
+
+		for budget >= generatedArmyCosts[0].Cost {
+			affordable := 0
+			for affordable < len(generatedArmyCosts) && generatedArmyCosts[affordable].Cost <= budget {
+				affordable++
+			}
+
+			choice := generatedArmyCosts[rng.Intn(affordable)]
+			budget -= choice.Cost
+
+			scenario.Armies = append(scenario.Armies, ScenarioArmy{
+				Name:       fmt.Sprintf("%s %s of %s", ordinal(bought[choice.Archetype]), choice.Archetype, actor),
+				Archetype:  choice.Archetype,
+				Allegiance: actor,
+			})
+
+			bought[choice.Archetype]++
+		}
+	}
+
+	return scenario, nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestOrdinal(t *testing.T) {
+	tests := []struct {
+		idx      int
+		expected string
+	}{
+		{idx: 0, expected: "First"},
+		{idx: 9, expected: "Tenth"},
+		{idx: 10, expected: "11th"},
+		{idx: 11, expected: "12th"},
+		{idx: 12, expected: "13th"},
+		{idx: 20, expected: "21st"},
+		{idx: 21, expected: "22nd"},
+		{idx: 22, expected: "23rd"},
+		{idx: 23, expected: "24th"},
+		{idx: 110, expected: "111th"},
+		{idx: 100, expected: "101st"},
+	}
+
+	for _, test := range tests {
+		if ordinal := ordinal(test.idx); ordinal != test.expected {
+			t.Errorf("ordinal(%d) = %s, expected %s", test.idx, ordinal, test.expected)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+)
+
+// HealingRules sets how quickly armies and settlements recover between battles. Armies resting in a
+// friendly settlement with no enemies about regain RestPercent of their maximum HP each turn, and
+// surgeons restore SurgeonPercent of the maximum HP of every other allied army alongside them,
+// wherever they are. Settlements with no enemies about regain a HP each turn for every
+// PopulationPerHP people living there.
+type HealingRules struct {
+	RestPercent     int
+	SurgeonPercent  int
+	PopulationPerHP uint
+}
+
+var defaultHealing = HealingRules{
+	RestPercent:     5,
+	SurgeonPercent:  10,
+	PopulationPerHP: 1000,
+}
+
+// Healing returns the healing rules for this world.
+func (s *World) Healing() *HealingRules {
+	if s.Rules == nil || s.Rules.Healing == nil {
+		return &defaultHealing
+	}
+
+	return s.Rules.Healing
+}
+
+// percentOf returns a percentage of a value, and at least 1 unless the percentage is 0.
+func percentOf(value, percent int) int {
+	if amount := value * percent / 100; amount > 0 || percent <= 0 {
+		return amount
+	}
+
+	return 1
+}
+
+// stepHealing lets surgeons tend to their allies and armies and settlements that aren't under
+// attack recover some of their HP.
+func (s *World) stepHealing(log *DocumentElement) bool {
+	var (
+		activityObserved = false
+		healing          = s.Healing()
+		healingList      = log.Element(UnorderedList)
+	)
+
+	healingList.Attributes["style"] = "list-style-type: none;"
+
+	armies := ArmyListFromMap(s.Armies).Sorted()
+
+	for _, surgeons := range armies {
+		if surgeons.Destroyed || !surgeons.Surgeons {
+			continue
+		}
+
+		for _, patient := range armies {
+			if patient == surgeons || patient.Destroyed || patient.Allegiance != surgeons.Allegiance || patient.LocationDescription() != surgeons.LocationDescription() {
+				continue
+			}
+
+			if healed := patient.HP.Heal(percentOf(patient.HP.Max, healing.SurgeonPercent)); healed > 0 {
+				activityObserved = true
+				healingList.Element(ListItem).Text = fmt.Sprintf("Army %s tends to army %s, restoring %d HP.",
+					NameLink(surgeons.Name), NameLink(patient.Name), healed)
+			}
+		}
+	}
+
+	for _, army := range armies {
+		if army.Destroyed || army.InTransit() {
+			continue
+		}
+
+		if settlement, found := s.Settlements[army.Location]; !found || settlement.Allegiance != army.Allegiance {
+			continue
+		} else if len(s.EnemiesAt(settlement.Name, army.Allegiance)) > 0 {
+			continue
+		}
+
+		if healed := army.HP.Heal(percentOf(army.HP.Max, healing.RestPercent)); healed > 0 {
+			activityObserved = true
+			healingList.Element(ListItem).Text = fmt.Sprintf("Army %s rests at %s and recovers %d HP.",
+				NameLink(army.Name), NameLink(army.Location), healed)
+		}
+	}
+
+	for _, settlement := range SettlementListFromMap(s.Settlements).Sorted() {
+		if healing.PopulationPerHP == 0 || len(s.EnemiesAt(settlement.Name, settlement.Allegiance)) > 0 {
+			continue
+		}
+
+		amount := int(settlement.Population / healing.PopulationPerHP)
+		if amount < 1 {
+			amount = 1
+		}
+
+		if healed := settlement.HP.Heal(amount); healed > 0 {
+			activityObserved = true
+			healingList.Element(ListItem).Text = fmt.Sprintf("Settlement %s recovers %d HP.", NameLink(settlement.Name), healed)
+		}
+	}
+
+	return activityObserved
+}
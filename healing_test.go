@@ -0,0 +1,57 @@
+package main
+
+import "testing"
+
+func TestStepHealing(t *testing.T) {
+	var (
+		world    = newTestWorld()
+		army     = world.Armies["First Host"]
+		aelyndar = world.Settlements["Aelyndar"]
+		surgeon  = &Army{
+			Name:       "Field Surgeons",
+			HP:         &HealthTracker{Current: 20, Max: 20},
+			Location:   "Aelyndar",
+			Allegiance: "Aundair",
+			Surgeons:   true,
+		}
+	)
+
+	world.Armies[surgeon.Name] = surgeon
+	army.HP.Current = 30
+	aelyndar.HP.Current = 90
+
+	if !world.stepHealing(Element(Division)) {
+		t.Fatalf("healing was not reported")
+	}
+
+	// Surgeons restore a tenth of the army's HP on top of the twentieth it recovers resting
+	if army.HP.Current != 37 {
+		t.Errorf("army has %d HP after a turn with surgeons, expected 37", army.HP.Current)
+	} else if aelyndar.HP.Current != 91 {
+		t.Errorf("Aelyndar has %d HP after a turn, expected 91", aelyndar.HP.Current)
+	}
+
+	army.HP.Current = 48
+	world.stepHealing(Element(Division))
+
+	if army.HP.Current != army.HP.Max {
+		t.Errorf("army has %d HP, expected healing to stop at %d", army.HP.Current, army.HP.Max)
+	}
+}
+
+func TestStepHealingUnderAttack(t *testing.T) {
+	var (
+		world = newTestSiege("")
+		army  = world.Armies["Army None"]
+		olath = world.Settlements["Olath"]
+	)
+
+	army.HP.Current = 30
+	olath.HP.Current = 90
+
+	if world.stepHealing(Element(Division)) {
+		t.Errorf("healing was reported with an army attacking Olath")
+	} else if army.HP.Current != 30 || olath.HP.Current != 90 {
+		t.Errorf("army has %d HP and Olath %d, expected neither to heal while fighting", army.HP.Current, olath.HP.Current)
+	}
+}
@@ -3,38 +3,9 @@ package main
 import (
 	"bufio"
 	"fmt"
-	"math/rand"
 	"os"
-	"path"
-	"time"
 )
 
-func writeNewState() {
-	fmt.Printf("No state file exists, creating a new one.\n")
-
-	newState := &Simulation{
-		Step:     0,
-		StateDir: "state",
-	}
-
-	if err := newState.Write(); err != nil {
-		panic(fmt.Sprintf("Failed to Write new state file: %v.", err))
-	}
-
-	os.Exit(0)
-}
-
-func checkStateFile() {
-	if _, err := os.Stat(path.Join("state", stateFilename)); err != nil {
-		if !os.IsNotExist(err) {
-
-			panic(fmt.Sprintf("Faild to open state file: %v.", err))
-		}
-
-		writeNewState()
-	}
-}
-
 func launchStdinReader(stdinC chan string) {
 	reader := bufio.NewReader(os.Stdin)
 
@@ -50,14 +21,17 @@ func launchStdinReader(stdinC chan string) {
 }
 
 func main() {
-	rand.Seed(time.Now().Unix())
-	checkStateFile()
+	if len(os.Args) < 2 {
+		printUsage()
+		os.Exit(2)
+	}
 
-	//stdinC := make(chan string)
-	//launchStdinReader(stdinC)
-	if simulation, err := LoadSimulation("state"); err != nil {
-		panic(fmt.Sprintf("Failed to load state: %v.", err))
-	} else if err := simulation.Turn(); err != nil {
-		fmt.Printf("Error executing simulation: %v.", err)
+	if command, found := findCommand(os.Args[1]); !found {
+		fmt.Printf("Unknown command %s.\n\n", os.Args[1])
+		printUsage()
+		os.Exit(2)
+	} else if err := command.Run(os.Args[2:]); err != nil {
+		fmt.Printf("Error: %v.\n", err)
+		os.Exit(1)
 	}
 }
@@ -3,8 +3,11 @@ package main
 import (
 	"fmt"
 	"github.com/BurntSushi/toml"
+	"io/ioutil"
+	"math/rand"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"sort"
 )
@@ -14,19 +17,42 @@ const (
 )
 
 type Simulation struct {
+	SchemaVersion int
+
 	Name string
 	Step int
 
+	// Seed is the root of every random roll made by the simulation. Each turn derives its own
+	// seed from it so that any step may be re-run to get the same result. TurnSeeds records the
+	// seed that was used for each step that has been run.
+	Seed      int64
+	TurnSeeds []int64
+
+	// Branch names the timeline this simulation follows, which is empty for the original. Lineage
+	// lists the timelines it was forked from, oldest first.
+	Branch  string        `toml:",omitempty"`
+	Lineage []BranchPoint
+
 	StateDir string `toml:"-"`
 	World    *World `toml:"-"`
 }
 
-func LoadSimulation(stateDir string) (*Simulation, error) {
+// LoadSimulationState reads the simulation's state file without loading its world.
+func LoadSimulationState(stateDir string) (*Simulation, error) {
 	state := &Simulation{
 		StateDir: stateDir,
 	}
 
-	if _, err := toml.DecodeFile(state.StatePath(), state); err != nil {
+	if err := decodeVersioned(state.StatePath(), simulationMigrations, state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+func LoadSimulation(stateDir string) (*Simulation, error) {
+	state, err := LoadSimulationState(stateDir)
+	if err != nil {
 		return nil, err
 	}
 
@@ -44,6 +70,8 @@ func LoadSimulation(stateDir string) (*Simulation, error) {
 }
 
 func (s *Simulation) Write() error {
+	s.SchemaVersion = SimulationSchemaVersion
+
 	if file, err := os.OpenFile(s.StatePath(), os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0644); err != nil {
 		return err
 	} else if err := toml.NewEncoder(file).Encode(s); err != nil {
@@ -58,31 +86,128 @@ func (s *Simulation) StatePath() string {
 }
 
 func (s *Simulation) WorldPath() string {
+	return s.WorldPathForStep(s.Step)
+}
+
+func (s *Simulation) WorldPathForStep(step int) string {
 	sanitizedName := strings.Replace(strings.TrimSpace(strings.ToLower(s.Name)), " ", "_", -1)
-	worldFilename := fmt.Sprintf("%s.%d.toml", sanitizedName, s.Step)
+	worldFilename := fmt.Sprintf("%s.%d.toml", sanitizedName, step)
 
 	return path.Join(s.StateDir, worldFilename)
 }
 
+func (s *Simulation) RenderedPath() string {
+	return path.Join(s.StateDir, fmt.Sprintf("rendered.%d.html", s.Step))
+}
+
+// DeriveTurnSeed mixes the simulation seed with a step number to get the seed for that step's
+// turn. This uses the SplitMix64 finalizer so that neighbouring steps get unrelated seeds.
+func (s *Simulation) DeriveTurnSeed(step int) int64 {
+	mixed := uint64(s.Seed) + uint64(step)*0x9E3779B97F4A7C15
+	mixed = (mixed ^ (mixed >> 30)) * 0xBF58476D1CE4E5B9
+	mixed = (mixed ^ (mixed >> 27)) * 0x94D049BB133111EB
+
+	return int64(mixed ^ (mixed >> 31))
+}
+
+func (s *Simulation) RollLogPath() string {
+	return path.Join(s.StateDir, fmt.Sprintf("rolls.%d.toml", s.Step))
+}
+
+// Discard sets the simulation back to an earlier step, deleting the worlds, reports and roll
+// logs of every step after it, including any left behind by older rewinds. Orders are kept so
+// that the turns may be run again.
+func (s *Simulation) Discard(step int) error {
+	entries, err := ioutil.ReadDir(s.StateDir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		// Step files are named like rolls.3.toml, with the step just before the extension
+		parts := strings.Split(entry.Name(), ".")
+		if len(parts) < 3 {
+			continue
+		}
+
+		discarded, err := strconv.Atoi(parts[len(parts)-2])
+		if err != nil || discarded <= step {
+			continue
+		}
+
+		past := &Simulation{Name: s.Name, StateDir: s.StateDir, Step: discarded}
+		for _, stale := range []string{past.WorldPath(), past.RenderedPath(), past.RollLogPath()} {
+			if stale != path.Join(s.StateDir, entry.Name()) {
+				continue
+			} else if err := os.Remove(stale); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.Step = step
+	if len(s.TurnSeeds) > step {
+		s.TurnSeeds = s.TurnSeeds[:step]
+	}
+
+	return s.Write()
+}
+
 func (s *Simulation) Turn() error {
-	output := &strings.Builder{}
+	return s.TurnWithSeed(s.DeriveTurnSeed(s.Step + 1))
+}
+
+// TurnWithSeed runs the next turn of the simulation using the given seed for every roll made
+// during the turn. The turn is played out on a copy of the world, so a turn that fails part way
+// through leaves the simulation as it was.
+func (s *Simulation) TurnWithSeed(seed int64) error {
+	var (
+		output = &strings.Builder{}
+		roller = NewRoller(rand.New(rand.NewSource(seed)))
+		step   = s.Step + 1
+	)
+
+	// Gather any orders the actors have submitted for this turn and step a copy of the world
+	world, err := s.World.Clone()
+	if err != nil {
+		return err
+	} else if orders, err := LoadOrders(s.StateDir, world, step); err != nil {
+		return err
+	} else if _, err := world.Turn(step, roller, orders, output); err != nil {
+		return fmt.Errorf("turn %d: %v", step, err)
+	}
 
-	// Increase our step counter then step the simulation
-	s.Step++
-	s.World.Turn(s.Step, output)
+	// Increase our step counter now that the turn is done
+	s.Step = step
+	s.World = world
+
+	// Record the seed for this step, forgetting any seeds from a timeline we've rewound past
+	if len(s.TurnSeeds) > s.Step-1 {
+		s.TurnSeeds = s.TurnSeeds[:s.Step-1]
+	}
+
+	for len(s.TurnSeeds) < s.Step-1 {
+		s.TurnSeeds = append(s.TurnSeeds, 0)
+	}
+
+	s.TurnSeeds = append(s.TurnSeeds, seed)
 
 	// Commit a new version of this world
 	if err := WriteWorld(s.WorldPath(), s.World); err != nil {
 		return err
 	}
 
-	renderedFileName := fmt.Sprintf("rendered.%d.html", s.Step)
-	if file, err := os.OpenFile(renderedFileName, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0644); err != nil {
+	if file, err := os.OpenFile(s.RenderedPath(), os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0644); err != nil {
 		return err
 	} else if _, err := file.WriteString(output.String()); err != nil {
 		return err
 	}
 
+	// Keep a record of every roll made this turn so that the results may be audited
+	if err := WriteRollLog(s.RollLogPath(), &RollLog{Turn: s.Step, Seed: seed, Rolls: roller.Rolls}); err != nil {
+		return err
+	}
+
 	// Commit our current state
 	if err := s.Write(); err != nil {
 		return err
@@ -96,14 +221,30 @@ type HealthTracker struct {
 	Max     int
 }
 
+// Damage takes HP away, stopping at 0. Damage rolls with a negative modifier can come out below
+// zero, which does nothing rather than healing.
 func (s *HealthTracker) Damage(amount int) {
-	if s.Current - amount < 0 {
+	if amount <= 0 {
+		return
+	} else if s.Current - amount < 0 {
 		s.Current = 0
 	} else {
 		s.Current -= amount
 	}
 }
 
+// Heal restores HP, stopping at the maximum. Returns how much HP was restored.
+func (s *HealthTracker) Heal(amount int) int {
+	if amount <= 0 || s.Current >= s.Max {
+		return 0
+	} else if s.Current+amount > s.Max {
+		amount = s.Max - s.Current
+	}
+
+	s.Current += amount
+	return amount
+}
+
 type FortificationType uint
 
 const (
@@ -113,11 +254,15 @@ const (
 	OuterWall = FortificationType(3)
 )
 
+// Fortification is one of a settlement's defenses. A fortification is breached once its HP
+// runs out, after which it no longer counts toward the settlement's AC until it is repaired.
 type Fortification struct {
 	Name            string
 	Type            FortificationType
 	DefenseModifier int
 	AttackModifier  int
+	HP              *HealthTracker
+	Breached        bool `toml:",omitempty"`
 }
 
 type Army struct {
@@ -128,8 +273,40 @@ type Army struct {
 	DamageRoll  RollSpec
 	Location    string
 	Destination string
+	Route       []string
+	ForceMarch  bool
+	Transit     *Transit
+	Target      string
 	Allegiance  string
 	Destroyed   bool
+
+	// Stance decides which armies and walls the army attacks and how the settlement it is in
+	// strikes back
+	Stance Stance `toml:",omitempty"`
+
+	// SiegeEngines lets an army batter down walls, putting the whole of its damage into a
+	// settlement's fortifications rather than half
+	SiegeEngines bool
+
+	// Surgeons tend to the wounded of every allied army alongside this one
+	Surgeons bool
+
+	// Morale runs from 0 to 100, and an army whose morale breaks is Routed, fleeing and taking
+	// no orders until it rallies
+	Morale int
+	Routed bool `toml:",omitempty"`
+}
+
+func (s *Army) InTransit() bool {
+	return s.Transit != nil
+}
+
+func (s *Army) LocationDescription() string {
+	if s.InTransit() {
+		return s.Transit.String()
+	}
+
+	return s.Location
 }
 
 func (s *Army) Damage(amount int) {
@@ -139,6 +316,15 @@ func (s *Army) Damage(amount int) {
 
 type ArmyList []*Army
 
+func ArmyListFromMap(source map[string]*Army) ArmyList {
+	var armies ArmyList
+	for _, army := range source {
+		armies = append(armies, army)
+	}
+
+	return armies
+}
+
 func (s ArmyList) Sorted() ArmyList {
 	sorted := s
 	sort.Sort(sorted)
@@ -167,59 +353,23 @@ type Settlement struct {
 	Allegiance     string
 	Occupied       bool
 	Population     uint
-}
-
-func (s *Settlement) AC() int {
-	var (
-		seenTypes    []FortificationType
-		settlementAC = 0
-	)
-
-	for _, fortification := range s.Fortifications {
-		seen := false
-		for _, seenType := range seenTypes {
-			if fortification.Type == seenType {
-				seen = true
-				break
-			}
-		}
-
-		if seen {
-			continue
-		}
-
-		settlementAC += fortification.DefenseModifier
-	}
 
-	return settlementAC
-}
+	// Repairing is set when the settlement's actor orders its damaged fortifications repaired
+	// and cleared once they are whole again
+	Repairing bool
 
-func (s *Settlement) attackModifier() int {
-	attackModifier := 0
-	for _, fortification := range s.Fortifications {
-		attackModifier += fortification.AttackModifier
-	}
+	// Construction holds the fortifications being built, which are worked on in order
+	Construction []ConstructionProject `toml:",omitempty"`
 
-	return attackModifier
-}
-
-func (s *Settlement) RollAttack() (int, int, error) {
-	if attackRoll, err := D20.Roll(); err != nil {
-		return 0, 0, err
-	} else if damageRoll, err := s.DamageRoll.Roll(); err != nil {
-		return 0, 0, err
-	} else {
-		return attackRoll + s.attackModifier(), damageRoll, nil
-	}
+	// Training holds the armies being raised from the settlement's population
+	Training []Recruit `toml:",omitempty"`
 }
 
 func (s *Settlement) AttackRoll() Die {
 	attackMod := s.attackModifier()
 
-	if attackMod > 0 {
-		return Die(fmt.Sprintf("d20+%d", attackMod))
-	} else if attackMod < 0 {
-		return Die(fmt.Sprintf("d20-%d", attackMod))
+	if attackMod != 0 {
+		return Die(fmt.Sprintf("d20%+d", attackMod))
 	}
 
 	return D20
@@ -255,6 +405,10 @@ func (s SettlementList) Less(i, j int) bool {
 	return s[i].Name < s[j].Name
 }
 
+// WorldActor is one of the sides in the war, along with the resources it holds.
 type WorldActor struct {
-	Name string
+	Name      string
+	Treasury  int
+	Food      int
+	Materials int
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// newTestSimulation starts a simulation of a generated world in a temporary state directory.
+func newTestSimulation(t *testing.T) *Simulation {
+	stateDir, err := ioutil.TempDir("", "warsim")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenario, err := GenerateScenario(GeneratorOptions{
+		Seed:                 7,
+		Actors:               2,
+		Settlements:          8,
+		FortificationDensity: 0.5,
+		ArmyBudget:           500,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	world, err := scenario.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	simulation := &Simulation{
+		Name:     scenario.Name,
+		Seed:     7,
+		StateDir: stateDir,
+		World:    world,
+	}
+
+	if err := WriteWorld(simulation.WorldPath(), world); err != nil {
+		t.Fatal(err)
+	} else if err := simulation.Write(); err != nil {
+		t.Fatal(err)
+	}
+
+	return simulation
+}
+
+func readFile(t *testing.T, path string) []byte {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return contents
+}
+
+func TestRewindAndRerun(t *testing.T) {
+	simulation := newTestSimulation(t)
+	defer os.RemoveAll(simulation.StateDir)
+
+	for turn := 0; turn < 4; turn++ {
+		if err := simulation.Turn(); err != nil {
+			t.Fatalf("turn %d failed: %v", turn+1, err)
+		}
+	}
+
+	var (
+		world   = readFile(t, simulation.WorldPath())
+		rolls   = readFile(t, simulation.RollLogPath())
+		discard = &Simulation{Name: simulation.Name, StateDir: simulation.StateDir, Step: 3}
+	)
+
+	if err := simulation.Discard(2); err != nil {
+		t.Fatalf("failed to rewind: %v", err)
+	} else if _, err := os.Stat(discard.WorldPath()); !os.IsNotExist(err) {
+		t.Errorf("world for step 3 was kept after rewinding to step 2")
+	}
+
+	rewound, err := LoadSimulation(simulation.StateDir)
+	if err != nil {
+		t.Fatalf("failed to load the rewound simulation: %v", err)
+	} else if rewound.Step != 2 {
+		t.Fatalf("rewound simulation is at step %d, expected 2", rewound.Step)
+	}
+
+	for turn := 2; turn < 4; turn++ {
+		if err := rewound.Turn(); err != nil {
+			t.Fatalf("turn %d failed after rewinding: %v", turn+1, err)
+		}
+	}
+
+	if rerun := readFile(t, rewound.WorldPath()); !bytes.Equal(world, rerun) {
+		t.Errorf("re-running turns 3 and 4 produced a different world")
+	}
+
+	if rerun := readFile(t, rewound.RollLogPath()); !bytes.Equal(rolls, rerun) {
+		t.Errorf("re-running turns 3 and 4 produced different rolls")
+	}
+}
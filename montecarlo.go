@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+const (
+	tiedLeader = "Tied"
+)
+
+// CampaignRun is the outcome of a single Monte Carlo run. FallTurns holds the turn each
+// settlement first fell to another actor, for the settlements that fell.
+type CampaignRun struct {
+	Seed      int64
+	FallTurns map[string]int
+	Survivors map[string]bool
+	Leader    string
+}
+
+// MonteCarlo runs the same world forward many times with different seeds and gathers how each
+// campaign turned out. Nothing is written to disk and no orders are given.
+type MonteCarlo struct {
+	World   *World
+	Step    int
+	Seed    int64
+	Runs    int
+	Turns   int
+	Workers int
+}
+
+// runSeed derives the seed of a single run from the Monte Carlo seed.
+func (s *MonteCarlo) runSeed(run int) int64 {
+	return (&Simulation{Seed: s.Seed}).DeriveTurnSeed(run)
+}
+
+func (s *MonteCarlo) run(run int) (*CampaignRun, error) {
+	world, err := s.World.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CampaignRun{
+		Seed:      s.runSeed(run),
+		FallTurns: make(map[string]int),
+		Survivors: make(map[string]bool),
+	}
+
+	startingAllegiance := make(map[string]string)
+	for name, settlement := range world.Settlements {
+		startingAllegiance[name] = settlement.Allegiance
+	}
+
+	seeds := &Simulation{Seed: result.Seed}
+	for turn := 1; turn <= s.Turns; turn++ {
+		step := s.Step + turn
+		if _, err := world.Turn(step, NewRoller(rand.New(rand.NewSource(seeds.DeriveTurnSeed(step)))), nil, nil); err != nil {
+			return nil, fmt.Errorf("run %d, turn %d: %v", run, step, err)
+		}
+
+		for name, settlement := range world.Settlements {
+			if _, fallen := result.FallTurns[name]; !fallen && settlement.Allegiance != startingAllegiance[name] {
+				result.FallTurns[name] = turn
+			}
+		}
+	}
+
+	for name, army := range world.Armies {
+		result.Survivors[name] = !army.Destroyed
+	}
+
+	result.Leader = tiedLeader
+	mostSettlements := -1
+
+	// Every actor is ranked, including those left holding nothing
+	settlementsByActor := world.SettlementsByActor()
+	for _, actor := range world.SortedActors() {
+		if held := len(settlementsByActor[actor.Name]); held > mostSettlements {
+			result.Leader = actor.Name
+			mostSettlements = held
+		} else if held == mostSettlements {
+			result.Leader = tiedLeader
+		}
+	}
+
+	return result, nil
+}
+
+// Run carries out every run across the workers. Results are returned in run order so that the
+// same seed always gives the same report.
+func (s *MonteCarlo) Run() ([]*CampaignRun, error) {
+	var (
+		results  = make([]*CampaignRun, s.Runs)
+		errors   = make([]error, s.Runs)
+		runs     = make(chan int)
+		finished sync.WaitGroup
+	)
+
+	for worker := 0; worker < s.Workers; worker++ {
+		finished.Add(1)
+
+		go func() {
+			defer finished.Done()
+
+			for run := range runs {
+				results[run], errors[run] = s.run(run)
+			}
+		}()
+	}
+
+	for run := 0; run < s.Runs; run++ {
+		runs <- run
+	}
+
+	close(runs)
+	finished.Wait()
+
+	for _, err := range errors {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+// OutcomeStatistic summarizes one outcome across every run. Turns holds the turn the outcome
+// happened on in each run where that is meaningful.
+type OutcomeStatistic struct {
+	Kind  string
+	Name  string
+	Count int
+	Runs  int
+	Turns []int
+}
+
+func (s *OutcomeStatistic) Rate() float64 {
+	return float64(s.Count) / float64(s.Runs)
+}
+
+func (s *OutcomeStatistic) MeanTurns() float64 {
+	total := 0
+	for _, turns := range s.Turns {
+		total += turns
+	}
+
+	return float64(total) / float64(len(s.Turns))
+}
+
+func (s *OutcomeStatistic) MedianTurns() float64 {
+	sorted := append([]int(nil), s.Turns...)
+	sort.Ints(sorted)
+
+	if middle := len(sorted) / 2; len(sorted)%2 == 1 {
+		return float64(sorted[middle])
+	} else {
+		return float64(sorted[middle-1]+sorted[middle]) / 2
+	}
+}
+
+func (s *OutcomeStatistic) MinTurns() int {
+	min := s.Turns[0]
+	for _, turns := range s.Turns {
+		if turns < min {
+			min = turns
+		}
+	}
+
+	return min
+}
+
+func (s *OutcomeStatistic) MaxTurns() int {
+	max := s.Turns[0]
+	for _, turns := range s.Turns {
+		if turns > max {
+			max = turns
+		}
+	}
+
+	return max
+}
+
+// CampaignStatistics gathers the outcome of every run into statistics for each settlement,
+// army and actor.
+func CampaignStatistics(world *World, results []*CampaignRun) (settlements, armies, leaders []*OutcomeStatistic) {
+	for _, settlement := range SettlementListFromMap(world.Settlements).Sorted() {
+		statistic := &OutcomeStatistic{Kind: "settlement", Name: settlement.Name, Runs: len(results)}
+		for _, result := range results {
+			if turn, fallen := result.FallTurns[settlement.Name]; fallen {
+				statistic.Count++
+				statistic.Turns = append(statistic.Turns, turn)
+			}
+		}
+
+		settlements = append(settlements, statistic)
+	}
+
+	for _, army := range ArmyListFromMap(world.Armies).Sorted() {
+		statistic := &OutcomeStatistic{Kind: "army", Name: army.Name, Runs: len(results)}
+		for _, result := range results {
+			if result.Survivors[army.Name] {
+				statistic.Count++
+			}
+		}
+
+		armies = append(armies, statistic)
+	}
+
+	var names []string
+	for _, actor := range world.SortedActors() {
+		names = append(names, actor.Name)
+	}
+
+	for _, name := range append(names, tiedLeader) {
+		statistic := &OutcomeStatistic{Kind: "leader", Name: name, Runs: len(results)}
+		for _, result := range results {
+			if result.Leader == name {
+				statistic.Count++
+			}
+		}
+
+		leaders = append(leaders, statistic)
+	}
+
+	return settlements, armies, leaders
+}
+
+func printCampaignStatistics(world *World, turns int, settlements, armies, leaders []*OutcomeStatistic) {
+	Printf("\nSettlements falling to another actor within %d turns", turns)
+	for _, statistic := range settlements {
+		if statistic.Count == 0 {
+			Printf("  %s (%s): never fell", statistic.Name, world.Settlements[statistic.Name].Allegiance)
+		} else {
+			Printf("  %s (%s): fell in %.1f%% of runs, after %.1f turns on average (median %.1f, %d - %d)",
+				statistic.Name, world.Settlements[statistic.Name].Allegiance, statistic.Rate()*100,
+				statistic.MeanTurns(), statistic.MedianTurns(), statistic.MinTurns(), statistic.MaxTurns())
+		}
+	}
+
+	Printf("\nArmies surviving %d turns", turns)
+	for _, statistic := range armies {
+		Printf("  %s (%s): survived %.1f%% of runs", statistic.Name, world.Armies[statistic.Name].Allegiance, statistic.Rate()*100)
+	}
+
+	Printf("\nActor holding the most settlements at turn %d", turns)
+	for _, statistic := range leaders {
+		Printf("  %s: %.1f%% of runs", statistic.Name, statistic.Rate()*100)
+	}
+}
+
+func writeCampaignCSV(path string, statistics []*OutcomeStatistic) error {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"kind", "name", "runs", "count", "rate", "mean_turns", "median_turns", "min_turns", "max_turns"})
+
+	for _, statistic := range statistics {
+		row := []string{
+			statistic.Kind,
+			statistic.Name,
+			strconv.Itoa(statistic.Runs),
+			strconv.Itoa(statistic.Count),
+			strconv.FormatFloat(statistic.Rate(), 'f', 4, 64),
+			"", "", "", "",
+		}
+
+		if len(statistic.Turns) > 0 {
+			row[5] = strconv.FormatFloat(statistic.MeanTurns(), 'f', 2, 64)
+			row[6] = strconv.FormatFloat(statistic.MedianTurns(), 'f', 1, 64)
+			row[7] = strconv.Itoa(statistic.MinTurns())
+			row[8] = strconv.Itoa(statistic.MaxTurns())
+		}
+
+		writer.Write(row)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func runMonteCarlo(args []string) error {
+	flags, stateDir := newFlagSet("montecarlo")
+	runs := flags.Int("runs", 1000, "number of campaigns to run")
+	turns := flags.Int("turns", 20, "number of turns in each campaign")
+	seed := flags.String("seed", "", "seed for the campaigns, defaults to the simulation seed")
+	workers := flags.Int("workers", runtime.NumCPU(), "number of campaigns to run at once")
+	csvPath := flags.String("csv", "", "file to write the statistics to as CSV")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	} else if *runs < 1 || *turns < 1 || *workers < 1 {
+		return fmt.Errorf("runs, turns and workers must each be at least 1")
+	}
+
+	simulation, err := LoadSimulation(*stateDir)
+	if err != nil {
+		return err
+	}
+
+	monteCarlo := &MonteCarlo{
+		World:   simulation.World,
+		Step:    simulation.Step,
+		Seed:    simulation.Seed,
+		Runs:    *runs,
+		Turns:   *turns,
+		Workers: *workers,
+	}
+
+	if len(*seed) > 0 {
+		if monteCarlo.Seed, err = strconv.ParseInt(*seed, 10, 64); err != nil {
+			return fmt.Errorf("%s is not a valid seed: %v", *seed, err)
+		}
+	}
+
+	results, err := monteCarlo.Run()
+	if err != nil {
+		return err
+	}
+
+	Printf("%s, %d runs of %d turns from step %d", simulation.Name, *runs, *turns, simulation.Step)
+	fmt.Printf("  Seed: %d\n", monteCarlo.Seed)
+
+	settlements, armies, leaders := CampaignStatistics(simulation.World, results)
+	printCampaignStatistics(simulation.World, *turns, settlements, armies, leaders)
+
+	if len(*csvPath) > 0 {
+		statistics := append(append(settlements, armies...), leaders...)
+		if err := writeCampaignCSV(*csvPath, statistics); err != nil {
+			return err
+		}
+
+		fmt.Printf("\nWrote statistics to %s\n", *csvPath)
+	}
+
+	return nil
+}
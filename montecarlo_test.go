@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+func TestMonteCarloLeader(t *testing.T) {
+	tests := []struct {
+		description string
+		allegiances map[string]string
+		expected    string
+	}{
+		{
+			description: "one actor holds the most settlements",
+			expected:    "Aundair",
+		},
+		{
+			description: "every settlement has fallen to one actor",
+			allegiances: map[string]string{"Aelyndar": "Thrane", "Thaliost": "Thrane", "Wroat": "Thrane"},
+			expected:    "Thrane",
+		},
+		{
+			description: "two actors hold as many settlements",
+			allegiances: map[string]string{"Aelyndar": "Thrane"},
+			expected:    tiedLeader,
+		},
+		{
+			description: "two actors are left with nothing",
+			allegiances: map[string]string{"Aelyndar": "Karrnath", "Thaliost": "Karrnath", "Wroat": "Karrnath", "Olath": "Karrnath"},
+			expected:    "Karrnath",
+		},
+	}
+
+	for _, test := range tests {
+		world := newTestWorld()
+		world.Actors["Karrnath"] = &WorldActor{Name: "Karrnath", Treasury: 100, Food: 100}
+		delete(world.Armies, "First Host")
+
+		for name, allegiance := range test.allegiances {
+			world.Settlements[name].Allegiance = allegiance
+		}
+
+		monteCarlo := &MonteCarlo{World: world, Seed: 1, Runs: 1, Turns: 1, Workers: 1}
+		if runs, err := monteCarlo.Run(); err != nil {
+			t.Errorf("%s: %v", test.description, err)
+		} else if runs[0].Leader != test.expected {
+			t.Errorf("%s: leader is %s, expected %s", test.description, runs[0].Leader, test.expected)
+		}
+	}
+
+	world := newTestWorld()
+	world.Settlements = make(map[string]*Settlement)
+	world.Armies = make(map[string]*Army)
+	world.Routes = nil
+
+	if runs, err := (&MonteCarlo{World: world, Seed: 1, Runs: 1, Turns: 1, Workers: 1}).Run(); err != nil {
+		t.Errorf("world without settlements: %v", err)
+	} else if runs[0].Leader != tiedLeader {
+		t.Errorf("world without settlements has leader %s, expected every actor to be tied with none", runs[0].Leader)
+	}
+}
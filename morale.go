@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+)
+
+const (
+	maxMorale = 100
+)
+
+// MoraleRules sets how an army's morale, from 0 to 100, rises and falls. Armies lose
+// DamagePercent of every percent of their maximum HP they lose, AllyLoss whenever an allied army
+// alongside them is destroyed or routs, MissLoss for every attack that misses and FumbleLoss for
+// every fumble. An army routs once its morale falls to BreakPoint. Armies in a friendly
+// settlement with no enemies about regain RallyGain morale each turn, and routed armies rally
+// once their morale is back to RallyPoint.
+type MoraleRules struct {
+	BreakPoint    int
+	RallyPoint    int
+	RallyGain     int
+	DamagePercent int
+	AllyLoss      int
+	MissLoss      int
+	FumbleLoss    int
+}
+
+var defaultMorale = MoraleRules{
+	BreakPoint:    25,
+	RallyPoint:    60,
+	RallyGain:     10,
+	DamagePercent: 100,
+	AllyLoss:      15,
+	MissLoss:      2,
+	FumbleLoss:    10,
+}
+
+// Morale returns the morale rules for this world.
+func (s *World) Morale() *MoraleRules {
+	if s.Rules == nil || s.Rules.Morale == nil {
+		return &defaultMorale
+	}
+
+	return s.Rules.Morale
+}
+
+// damageLoss returns the morale an army loses from taking damage.
+func (s *MoraleRules) damageLoss(army *Army, damage int) int {
+	if damage <= 0 || army.HP.Max <= 0 {
+		return 0
+	}
+
+	return percentOf(damage*100/army.HP.Max, s.DamagePercent)
+}
+
+// safe returns true if the army is in a friendly settlement with no enemies about.
+func (s *World) safe(army *Army) bool {
+	if army.InTransit() {
+		return false
+	} else if settlement, found := s.Settlements[army.Location]; !found || settlement.Allegiance != army.Allegiance {
+		return false
+	}
+
+	return len(s.EnemiesAt(army.Location, army.Allegiance)) == 0
+}
+
+// nearestRefuge finds the closest friendly settlement other than where the army is now that it
+// can reach without marching through enemy territory.
+func (s *World) nearestRefuge(army *Army) (string, bool) {
+	var (
+		refuge    string
+		found     = false
+		bestTurns = 0
+		scout     = *army
+	)
+
+	scout.ForceMarch = false
+
+	for _, settlement := range SettlementListFromMap(s.Settlements).Sorted() {
+		if settlement.Allegiance != army.Allegiance || settlement.Name == army.Location {
+			continue
+		}
+
+		scout.Destination = settlement.Name
+		path, err := s.PlanRoute(&scout)
+		if err != nil {
+			continue
+		}
+
+		turns, from := 0, army.Location
+		for _, stop := range path {
+			route, _ := s.RouteBetween(from, stop)
+			turns, from = turns+route.Turns(), stop
+		}
+
+		if !found || turns < bestTurns {
+			refuge, found, bestTurns = settlement.Name, true, turns
+		}
+	}
+
+	return refuge, found
+}
+
+// shakeArmy lowers an army's morale and routs it if its morale breaks, in which case the army
+// that broke it, if any, gives chase.
+func (s *World) shakeArmy(roller *Roller, army *Army, loss int, pursuer *Army, actionList *DocumentElement) error {
+	if army.Destroyed || army.Routed || loss <= 0 {
+		return nil
+	}
+
+	if army.Morale -= loss; army.Morale < 0 {
+		army.Morale = 0
+	}
+
+	if army.Morale <= s.Morale().BreakPoint {
+		return s.routArmy(roller, army, pursuer, actionList)
+	}
+
+	return nil
+}
+
+// woundArmy applies damage to an army along with the blow to its morale. The army that dealt
+// the damage, if any, pursues the army if it routs. Returns true if the damage destroyed the
+// army, leaving the caller to report it and shake its allies.
+func (s *World) woundArmy(roller *Roller, army *Army, damage int, attacker *Army, actionList *DocumentElement) (bool, error) {
+	if army.Damage(damage); army.Destroyed {
+		return true, nil
+	}
+
+	return false, s.shakeArmy(roller, army, s.Morale().damageLoss(army, damage), attacker, actionList)
+}
+
+// alliesShaken lowers the morale of every allied army alongside an army that has been destroyed
+// or has routed.
+func (s *World) alliesShaken(roller *Roller, fallen *Army, actionList *DocumentElement) error {
+	if len(fallen.Location) == 0 {
+		return nil
+	}
+
+	for _, ally := range s.ArmiesAt(fallen.Location).Sorted() {
+		if ally == fallen || ally.Allegiance != fallen.Allegiance {
+			continue
+		}
+
+		if err := s.shakeArmy(roller, ally, s.Morale().AllyLoss, nil, actionList); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// routArmy sends an army whose morale has broken fleeing toward the nearest friendly settlement,
+// or scatters it if it has nowhere to run. The pursuer, if any, cuts down some of the fleeing
+// army as it goes.
+func (s *World) routArmy(roller *Roller, army *Army, pursuer *Army, actionList *DocumentElement) error {
+	army.Routed = true
+	army.Target = ""
+	army.Stance = ""
+	army.ForceMarch = false
+
+	refuge, found := s.nearestRefuge(army)
+	if !found {
+		army.Destroyed = true
+		actionList.Element(ListItem).Text = fmt.Sprintf("Army %s breaks and scatters with nowhere to run!", NameLink(army.Name))
+
+		return s.alliesShaken(roller, army, actionList)
+	}
+
+	army.Destination = refuge
+	actionList.Element(ListItem).Text = fmt.Sprintf("Army %s breaks and routs toward %s!", NameLink(army.Name), NameLink(refuge))
+
+	if pursuer != nil && !pursuer.Destroyed && !pursuer.Routed {
+		damage, err := s.rollDamage(roller, pursuer.DamageRoll, fmt.Sprintf("%s pursues %s", pursuer.Name, army.Name), AttackHit)
+		if err != nil {
+			return err
+		}
+
+		entry := actionList.Element(ListItem)
+		entry.Text = fmt.Sprintf("Army %s pursues the fleeing army %s, rolling %d for damage!",
+			NameLink(pursuer.Name), NameLink(army.Name), damage.Total)
+		entry.Push(RollDetails(damage))
+
+		if army.Damage(damage.Total); army.Destroyed {
+			actionList.Element(ListItem).Text = fmt.Sprintf("Army %s has cut down army %s as it fled!",
+				NameLink(pursuer.Name), NameLink(army.Name))
+		}
+	}
+
+	return s.alliesShaken(roller, army, actionList)
+}
+
+// stepMorale lets armies that are safe in a friendly settlement recover their morale, rallying
+// those that had routed.
+func (s *World) stepMorale(log *DocumentElement) bool {
+	var (
+		activityObserved = false
+		morale           = s.Morale()
+		moraleList       = log.Element(UnorderedList)
+	)
+
+	moraleList.Attributes["style"] = "list-style-type: none;"
+
+	for _, army := range ArmyListFromMap(s.Armies).Sorted() {
+		if army.Destroyed || army.Morale >= maxMorale || !s.safe(army) {
+			continue
+		}
+
+		if army.Morale += morale.RallyGain; army.Morale > maxMorale {
+			army.Morale = maxMorale
+		}
+
+		if army.Routed && army.Morale >= morale.RallyPoint {
+			activityObserved = true
+
+			army.Routed = false
+			army.Destination = army.Location
+
+			moraleList.Element(ListItem).Text = fmt.Sprintf("Army %s has rallied at %s.", NameLink(army.Name), NameLink(army.Location))
+		}
+	}
+
+	return activityObserved
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestWoundArmyShakesMorale(t *testing.T) {
+	var (
+		world  = newTestWorld()
+		army   = world.Armies["First Host"]
+		roller = NewRoller(rand.New(rand.NewSource(1)))
+	)
+
+	// Losing a fifth of its HP costs the army a fifth of its morale
+	if destroyed, err := world.woundArmy(roller, army, 10, nil, Element(UnorderedList)); err != nil || destroyed {
+		t.Fatalf("army destroyed %t (%v) by 10 damage", destroyed, err)
+	} else if army.Morale != maxMorale-20 {
+		t.Errorf("army has %d morale after losing 10 of 50 HP, expected %d", army.Morale, maxMorale-20)
+	}
+}
+
+func TestShakenArmyRouts(t *testing.T) {
+	var (
+		world   = newTestSiege("", StanceHold)
+		army    = world.Armies["Army None"]
+		roller  = NewRoller(rand.New(rand.NewSource(1)))
+		pursuer = &Army{Name: "Olath Guard", HP: &HealthTracker{Current: 40, Max: 40}, DamageRoll: RollSpec{Die("3")}, Allegiance: "Thrane"}
+	)
+
+	army.Morale = 30
+
+	if err := world.shakeArmy(roller, army, 10, pursuer, Element(UnorderedList)); err != nil {
+		t.Fatal(err)
+	}
+
+	if !army.Routed || army.Destination != "Wroat" {
+		t.Errorf("army with broken morale is heading for %q (routed %t), expected it to rout to Wroat", army.Destination, army.Routed)
+	} else if army.HP.Current != 47 {
+		t.Errorf("routing army has %d HP, expected the pursuit to cost it 3", army.HP.Current)
+	}
+
+	// Its ally at Olath sees it rout, but keeps its nerve
+	if ally := world.Armies["Army hold"]; ally.Morale != maxMorale-defaultMorale.AllyLoss || ally.Routed {
+		t.Errorf("ally has %d morale and routed %t, expected %d", ally.Morale, ally.Routed, maxMorale-defaultMorale.AllyLoss)
+	}
+
+	if err := world.applyOrder("Aundair", Order{Army: army.Name, Type: MoveOrder, Target: "Olath"}); err == nil {
+		t.Errorf("routed army took a move order")
+	}
+}
+
+func TestShakenArmyScattersWithNowhereToRun(t *testing.T) {
+	var (
+		world  = newTestSiege("")
+		army   = world.Armies["Army None"]
+		roller = NewRoller(rand.New(rand.NewSource(1)))
+	)
+
+	for _, settlement := range world.Settlements {
+		settlement.Allegiance = "Thrane"
+	}
+
+	army.Morale = 30
+	if err := world.shakeArmy(roller, army, 10, nil, Element(UnorderedList)); err != nil {
+		t.Fatal(err)
+	} else if !army.Destroyed {
+		t.Errorf("army with nowhere to run was not scattered")
+	}
+}
+
+func TestStepMoraleRallies(t *testing.T) {
+	var (
+		world = newTestWorld()
+		army  = world.Armies["First Host"]
+	)
+
+	army.Routed = true
+	army.Morale = defaultMorale.RallyPoint - defaultMorale.RallyGain
+
+	if !world.stepMorale(Element(Division)) {
+		t.Errorf("rallying was not reported")
+	} else if army.Routed || army.Morale != defaultMorale.RallyPoint {
+		t.Errorf("army has %d morale and routed %t, expected it to rally with %d", army.Morale, army.Routed, defaultMorale.RallyPoint)
+	}
+
+	// Armies among enemies don't recover
+	army.Morale = 50
+	army.Location = "Olath"
+	army.Destination = "Olath"
+
+	if world.stepMorale(Element(Division)); army.Morale != 50 {
+		t.Errorf("army at enemy-held Olath recovered to %d morale", army.Morale)
+	}
+}
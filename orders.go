@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	ordersDirname = "orders"
+)
+
+type OrderType string
+
+const (
+	MoveOrder    OrderType = "move"
+	HoldOrder    OrderType = "hold"
+	AttackOrder  OrderType = "attack"
+	SiegeOrder   OrderType = "siege"
+	AssaultOrder OrderType = "assault"
+	StanceOrder  OrderType = "stance"
+	RepairOrder  OrderType = "repair"
+	BuildOrder   OrderType = "build"
+	RecruitOrder OrderType = "recruit"
+)
+
+// Order is a single instruction from an actor to one of their armies or settlements. Target names
+// the settlement to move to, besiege or assault, the army to attack, the stance to take, the
+// blueprint to build or the archetype to recruit. Orders given to a settlement, such as repairing
+// or building fortifications, name the Settlement instead of an Army.
+type Order struct {
+	Army       string `toml:",omitempty"`
+	Settlement string `toml:",omitempty"`
+	Type       OrderType
+	Target     string
+	ForceMarch bool
+}
+
+// SettlementOrder returns true if the order is given to a settlement rather than an army.
+func (s Order) SettlementOrder() bool {
+	return len(s.Settlement) > 0
+}
+
+func (s Order) String() string {
+	subject := s.Army
+	if s.SettlementOrder() {
+		subject = s.Settlement
+	}
+
+	if len(s.Target) > 0 {
+		return fmt.Sprintf("%s %s %s", subject, s.Type, s.Target)
+	}
+
+	return fmt.Sprintf("%s %s", subject, s.Type)
+}
+
+// OrderSet holds every order an actor has submitted for a single turn.
+type OrderSet struct {
+	Actor  string `toml:"-"`
+	Orders []Order
+}
+
+func OrdersPath(stateDir, actor string, step int) string {
+	return path.Join(stateDir, ordersDirname, fmt.Sprintf("%s.%d.toml", DocumentID(actor), step))
+}
+
+// LoadOrders reads the order files submitted by each actor for the given step. Actors that
+// haven't submitted orders are skipped.
+func LoadOrders(stateDir string, world *World, step int) ([]*OrderSet, error) {
+	var orderSets []*OrderSet
+
+	for _, actor := range world.SortedActors() {
+		ordersPath := OrdersPath(stateDir, actor.Name, step)
+
+		if _, err := os.Stat(ordersPath); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		fmt.Printf("Loading orders %s\n", ordersPath)
+
+		orderSet := &OrderSet{
+			Actor: actor.Name,
+		}
+
+		if _, err := toml.DecodeFile(ordersPath, orderSet); err != nil {
+			return nil, fmt.Errorf("failed to read orders for %s: %v", actor.Name, err)
+		}
+
+		orderSets = append(orderSets, orderSet)
+	}
+
+	return orderSets, nil
+}
+
+// applyOrder checks that an order is valid for the actor issuing it and then carries it out. An
+// error is returned describing why the order was rejected.
+func (s *World) applyOrder(actor string, order Order) error {
+	if order.SettlementOrder() {
+		return s.applySettlementOrder(actor, order)
+	}
+
+	army, found := s.Armies[order.Army]
+	if !found || army.Allegiance != actor {
+		return fmt.Errorf("%s does not command an army named %s", actor, order.Army)
+	} else if army.Destroyed {
+		return fmt.Errorf("army %s has been destroyed", order.Army)
+	} else if army.Routed {
+		return fmt.Errorf("army %s has routed and won't take orders until it rallies", order.Army)
+	}
+
+	switch order.Type {
+	case MoveOrder:
+		if _, found := s.Settlements[order.Target]; !found {
+			return fmt.Errorf("there is no settlement named %s", order.Target)
+		}
+
+		army.Destination = order.Target
+		army.Target = ""
+		army.Stance = ""
+
+	case HoldOrder:
+		// Armies on the road halt at the next settlement they reach
+		if army.InTransit() {
+			army.Destination = army.Transit.To
+		} else {
+			army.Destination = army.Location
+		}
+
+		army.Target = ""
+		army.Stance = StanceHold
+
+	case AttackOrder:
+		if target, found := s.Armies[order.Target]; !found || target.Destroyed {
+			return fmt.Errorf("there is no army named %s to attack", order.Target)
+		} else if target.Allegiance == actor {
+			return fmt.Errorf("army %s is not an enemy of %s", order.Target, actor)
+		}
+
+		army.Target = order.Target
+		army.Stance = ""
+
+	case SiegeOrder, AssaultOrder:
+		if target, found := s.Settlements[order.Target]; !found {
+			return fmt.Errorf("there is no settlement named %s", order.Target)
+		} else if target.Allegiance == actor {
+			return fmt.Errorf("settlement %s is already held by %s", order.Target, actor)
+		}
+
+		army.Destination = order.Target
+		army.Target = ""
+
+		if order.Type == SiegeOrder {
+			army.Stance = StanceSiege
+		} else {
+			army.Stance = StanceAssault
+		}
+
+	case StanceOrder:
+		if stance := Stance(order.Target); len(stance) == 0 || !stance.Known() {
+			return fmt.Errorf("unknown stance %q", order.Target)
+		} else {
+			army.Stance = stance
+		}
+
+		// Armies that no longer go looking for a fight give up the army they were chasing
+		if !army.Stance.EngagesArmies() {
+			army.Target = ""
+		}
+
+	default:
+		return fmt.Errorf("unknown order type %q", order.Type)
+	}
+
+	army.ForceMarch = order.ForceMarch
+	return nil
+}
+
+// applySettlementOrder checks and carries out an order given to one of the actor's settlements.
+func (s *World) applySettlementOrder(actor string, order Order) error {
+	settlement, found := s.Settlements[order.Settlement]
+	if !found || settlement.Allegiance != actor {
+		return fmt.Errorf("%s does not hold a settlement named %s", actor, order.Settlement)
+	}
+
+	switch order.Type {
+	case RepairOrder:
+		damaged := false
+		for _, fortification := range settlement.Fortifications {
+			damaged = damaged || fortification.Damaged()
+		}
+
+		if !damaged {
+			return fmt.Errorf("settlement %s has no damaged fortifications to repair", order.Settlement)
+		}
+
+		settlement.Repairing = true
+
+	case BuildOrder:
+		return s.queueConstruction(s.Actors[actor], settlement, order.Target)
+
+	case RecruitOrder:
+		return s.queueRecruit(s.Actors[actor], settlement, order.Target)
+
+	default:
+		return fmt.Errorf("unknown settlement order type %q", order.Type)
+	}
+
+	return nil
+}
+
+func (s *World) applyOrders(orderSets []*OrderSet, log *DocumentElement) {
+	if len(orderSets) == 0 {
+		return
+	}
+
+	var rejected []string
+
+	log.Element(H3).Text = "Orders"
+
+	orderList := log.Element(UnorderedList)
+	orderList.Attributes["style"] = "list-style-type: none;"
+
+	for _, orderSet := range orderSets {
+		for _, order := range orderSet.Orders {
+			if err := s.applyOrder(orderSet.Actor, order); err != nil {
+				rejected = append(rejected, fmt.Sprintf("%s ordered %s: %v.", orderSet.Actor, order, err))
+			} else {
+				orderList.Element(ListItem).Text = fmt.Sprintf("%s ordered %s.", orderSet.Actor, order)
+			}
+		}
+	}
+
+	if len(rejected) > 0 {
+		log.Element(H3).Text = "Rejected Orders"
+
+		rejectedList := log.Element(UnorderedList)
+		rejectedList.Attributes["style"] = "list-style-type: none;"
+
+		for _, reason := range rejected {
+			rejectedList.Element(ListItem).Text = reason
+		}
+	}
+}
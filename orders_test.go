@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestApplyOrder(t *testing.T) {
+	tests := []struct {
+		actor    string
+		order    Order
+		rejected string
+	}{
+		{actor: "Aundair", order: Order{Army: "First Host", Type: MoveOrder, Target: "Wroat"}},
+		{actor: "Aundair", order: Order{Army: "First Host", Type: AssaultOrder, Target: "Olath"}},
+		{actor: "Aundair", order: Order{Army: "First Host", Type: HoldOrder}},
+		{actor: "Thrane", order: Order{Army: "First Host", Type: MoveOrder, Target: "Wroat"}, rejected: "Thrane does not command an army named First Host"},
+		{actor: "Aundair", order: Order{Army: "First Host", Type: MoveOrder, Target: "Sharn"}, rejected: "there is no settlement named Sharn"},
+		{actor: "Aundair", order: Order{Army: "First Host", Type: SiegeOrder, Target: "Wroat"}, rejected: "settlement Wroat is already held by Aundair"},
+		{actor: "Aundair", order: Order{Army: "First Host", Type: "retreat"}, rejected: `unknown order type "retreat"`},
+		{actor: "Aundair", order: Order{Settlement: "Olath", Type: RepairOrder}, rejected: "Aundair does not hold a settlement named Olath"},
+	}
+
+	for _, test := range tests {
+		world := newTestWorld()
+
+		if err := world.applyOrder(test.actor, test.order); len(test.rejected) == 0 && err != nil {
+			t.Errorf("%s ordered %s: %v", test.actor, test.order, err)
+		} else if len(test.rejected) > 0 && (err == nil || err.Error() != test.rejected) {
+			t.Errorf("%s ordered %s: rejected with %v, expected %q", test.actor, test.order, err, test.rejected)
+		}
+	}
+}
+
+func TestApplyOrdersCarriesThemOut(t *testing.T) {
+	var (
+		world = newTestWorld()
+		army  = world.Armies["First Host"]
+		log   = Element(Division)
+	)
+
+	world.applyOrders([]*OrderSet{
+		{Actor: "Aundair", Orders: []Order{{Army: "First Host", Type: AssaultOrder, Target: "Olath", ForceMarch: true}}},
+		{Actor: "Thrane", Orders: []Order{{Army: "First Host", Type: HoldOrder}}},
+	}, log)
+
+	if army.Destination != "Olath" || army.Stance != StanceAssault || !army.ForceMarch {
+		t.Errorf("army is heading for %q with stance %q, expected a forced march to assault Olath", army.Destination, army.Stance)
+	}
+
+	if report := log.String(); !strings.Contains(report, "Aundair ordered First Host assault Olath.") {
+		t.Errorf("report does not list the accepted order:\n%s", report)
+	} else if !strings.Contains(report, "Thrane ordered First Host hold: Thrane does not command an army named First Host.") {
+		t.Errorf("report does not list the rejected order:\n%s", report)
+	}
+}
+
+func TestLoadOrders(t *testing.T) {
+	stateDir, err := ioutil.TempDir("", "warsim")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(stateDir)
+
+	world := newTestWorld()
+	ordersPath := OrdersPath(stateDir, "Aundair", 3)
+
+	if err := os.MkdirAll(path.Dir(ordersPath), 0755); err != nil {
+		t.Fatal(err)
+	} else if err := ioutil.WriteFile(ordersPath, []byte("[[Orders]]\nArmy = \"First Host\"\nType = \"move\"\nTarget = \"Wroat\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	orderSets, err := LoadOrders(stateDir, world, 3)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(orderSets) != 1 || orderSets[0].Actor != "Aundair" {
+		t.Fatalf("loaded %d order sets, expected only Aundair's", len(orderSets))
+	}
+
+	expected := Order{Army: "First Host", Type: MoveOrder, Target: "Wroat"}
+	if orders := orderSets[0].Orders; len(orders) != 1 || orders[0] != expected {
+		t.Errorf("loaded orders %v, expected %v", orders, expected)
+	}
+
+	if orderSets, err := LoadOrders(stateDir, world, 4); err != nil || len(orderSets) != 0 {
+		t.Errorf("loaded %d order sets (%v) for a step without orders, expected none", len(orderSets), err)
+	}
+}
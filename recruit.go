@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// Armies raised in an occupied settlement start with only this share of their HP, as
+	// conscripts desert the occupier
+	occupiedRecruitPercent = 50
+)
+
+// RecruitArchetype is a kind of army that actors may raise at their settlements. Raising one
+// costs Cost gold and Food from the actor, draws Population people from the settlement and
+// takes Turns turns of training before the army takes the field.
+type RecruitArchetype struct {
+	ArmyArchetype
+
+	Cost       int
+	Food       int
+	Population uint
+	Turns      int
+}
+
+// Recruit is an army in training at a settlement.
+type Recruit struct {
+	Name      string
+	Archetype string
+	Occupied  bool `toml:",omitempty"`
+	TurnsLeft int
+}
+
+// The armies that may be raised in worlds whose rules don't list their own
+var defaultRecruits = map[string]RecruitArchetype{
+	"Levy": {
+		ArmyArchetype: ArmyArchetype{
+			HP:         40,
+			AC:         17,
+			AttackRoll: RollSpec{"d20+2"},
+			DamageRoll: RollSpec{"d6"},
+		},
+		Cost:       60,
+		Food:       20,
+		Population: 1000,
+		Turns:      2,
+	},
+	"Regiment": {
+		ArmyArchetype: ArmyArchetype{
+			HP:         60,
+			AC:         19,
+			AttackRoll: RollSpec{"d20+4"},
+			DamageRoll: RollSpec{"d8"},
+		},
+		Cost:       100,
+		Food:       30,
+		Population: 1500,
+		Turns:      3,
+	},
+	"Knights": {
+		ArmyArchetype: ArmyArchetype{
+			HP:         45,
+			AC:         22,
+			AttackRoll: RollSpec{"d20+6"},
+			DamageRoll: RollSpec{"d8+2"},
+		},
+		Cost:       140,
+		Food:       40,
+		Population: 800,
+		Turns:      4,
+	},
+}
+
+// Recruits returns the kinds of army that may be raised in this world.
+func (s *World) Recruits() map[string]RecruitArchetype {
+	if s.Rules == nil || s.Rules.Recruits == nil {
+		return defaultRecruits
+	}
+
+	return s.Rules.Recruits
+}
+
+// Price returns what it costs the actor to raise the army.
+func (s RecruitArchetype) Price() Resources {
+	return Resources{
+		Gold: s.Cost,
+		Food: s.Food,
+	}
+}
+
+// Army returns the army the archetype raises, which starts with less HP if it was raised in an
+// occupied settlement.
+func (s RecruitArchetype) Army(recruit Recruit, settlement *Settlement) *Army {
+	currentHP := s.HP
+	if recruit.Occupied {
+		currentHP = s.HP * occupiedRecruitPercent / 100
+	}
+
+	return &Army{
+		Name:         recruit.Name,
+		AC:           s.AC,
+		AttackRoll:   s.AttackRoll,
+		DamageRoll:   s.DamageRoll,
+		SiegeEngines: s.SiegeEngines,
+		Surgeons:     s.Surgeons,
+		Morale:       maxMorale,
+		Location:     settlement.Name,
+		Destination:  settlement.Name,
+		Allegiance:   settlement.Allegiance,
+		HP: &HealthTracker{
+			Current: currentHP,
+			Max:     s.HP,
+		},
+	}
+}
+
+// TrainingDescription describes the armies in training at the settlement.
+func (s *Settlement) TrainingDescription() string {
+	if len(s.Training) == 0 {
+		return "None"
+	}
+
+	var recruits []string
+	for _, recruit := range s.Training {
+		recruits = append(recruits, fmt.Sprintf("%s (%s, %d turns left)", recruit.Name, recruit.Archetype, recruit.TurnsLeft))
+	}
+
+	return strings.Join(recruits, ", ")
+}
+
+// armyNameTaken returns true if an army or an army in training already has the name.
+func (s *World) armyNameTaken(name string) bool {
+	if _, found := s.Armies[name]; found {
+		return true
+	}
+
+	for _, settlement := range s.Settlements {
+		for _, recruit := range settlement.Training {
+			if recruit.Name == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// recruitName picks a name for a new army that no other army has, such as
+// "First Levy of Flamekeep".
+func (s *World) recruitName(archetype string, settlement *Settlement) string {
+	for idx := 0; ; idx++ {
+		if name := fmt.Sprintf("%s %s of %s", ordinal(idx), archetype, settlement.Name); !s.armyNameTaken(name) {
+			return name
+		}
+	}
+}
+
+// queueRecruit checks that the actor may raise the army at the settlement and, if so, pays for
+// it, draws the recruits from the settlement's population and starts their training.
+func (s *World) queueRecruit(actor *WorldActor, settlement *Settlement, name string) error {
+	archetype, found := s.Recruits()[name]
+	if !found {
+		return fmt.Errorf("there is no army archetype named %s to recruit", name)
+	} else if settlement.Population <= archetype.Population {
+		return fmt.Errorf("%s needs %d recruits but settlement %s only has a population of %d",
+			name, archetype.Population, settlement.Name, settlement.Population)
+	} else if !actor.Afford(archetype.Price()) {
+		return fmt.Errorf("%s costs %s but %s only holds %s", name, archetype.Price(), actor.Name, actor.Holdings())
+	}
+
+	actor.Spend(archetype.Price())
+	settlement.Population -= archetype.Population
+	settlement.Training = append(settlement.Training, Recruit{
+		Name:      s.recruitName(name, settlement),
+		Archetype: name,
+		Occupied:  settlement.Occupied,
+		TurnsLeft: archetype.Turns,
+	})
+
+	return nil
+}
+
+// stepTraining trains the armies being raised at every settlement, sending them into the field
+// once their training is done. Training can't go on while enemy armies are in the settlement.
+func (s *World) stepTraining(log *DocumentElement) bool {
+	var (
+		activityObserved = false
+		trainingList     = log.Element(UnorderedList)
+	)
+
+	trainingList.Attributes["style"] = "list-style-type: none;"
+
+	for _, settlement := range SettlementListFromMap(s.Settlements).Sorted() {
+		if len(settlement.Training) == 0 {
+			continue
+		}
+
+		if enemies := s.EnemiesAt(settlement.Name, settlement.Allegiance); len(enemies) > 0 {
+			trainingList.Element(ListItem).Text = fmt.Sprintf("Training at %s is halted while army %s is present.",
+				NameLink(settlement.Name), NameLink(enemies[0].Name))
+			continue
+		}
+
+		activityObserved = true
+
+		var stillTraining []Recruit
+		for _, recruit := range settlement.Training {
+			if recruit.TurnsLeft--; recruit.TurnsLeft > 0 {
+				stillTraining = append(stillTraining, recruit)
+				continue
+			}
+
+			army := s.Recruits()[recruit.Archetype].Army(recruit, settlement)
+			s.Armies[army.Name] = army
+
+			trainingList.Element(ListItem).Text = fmt.Sprintf("Settlement %s has raised army %s!",
+				NameLink(settlement.Name), NameLink(army.Name))
+		}
+
+		settlement.Training = stillTraining
+	}
+
+	return activityObserved
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestQueueRecruit(t *testing.T) {
+	var (
+		world   = newTestWorld()
+		aundair = world.Actors["Aundair"]
+		wroat   = world.Settlements["Wroat"]
+	)
+
+	aundair.Treasury = 150
+	wroat.Population = 5000
+
+	for _, name := range []string{"First Levy of Wroat", "Second Levy of Wroat"} {
+		if err := world.applyOrder("Aundair", Order{Settlement: "Wroat", Type: RecruitOrder, Target: "Levy"}); err != nil {
+			t.Fatalf("recruiting %s: %v", name, err)
+		} else if recruit := wroat.Training[len(wroat.Training)-1]; recruit.Name != name || recruit.TurnsLeft != 2 {
+			t.Errorf("recruited %s with %d turns left, expected %s with 2", recruit.Name, recruit.TurnsLeft, name)
+		}
+	}
+
+	if holdings := aundair.Holdings(); holdings != (Resources{Gold: 30, Food: 60}) || wroat.Population != 3000 {
+		t.Errorf("Aundair holds %s and Wroat has %d people after recruiting two levies", holdings, wroat.Population)
+	}
+
+	tests := []struct {
+		settlement string
+		archetype  string
+		rejected   string
+	}{
+		{settlement: "Wroat", archetype: "Levy", rejected: "Levy costs 60 gold, 20 food, 0 materials but Aundair only holds 30 gold, 60 food, 0 materials"},
+		{settlement: "Wroat", archetype: "Dragons", rejected: "there is no army archetype named Dragons to recruit"},
+		{settlement: "Aelyndar", archetype: "Regiment", rejected: "Regiment needs 1500 recruits but settlement Aelyndar only has a population of 1000"},
+	}
+
+	for _, test := range tests {
+		if err := world.applyOrder("Aundair", Order{Settlement: test.settlement, Type: RecruitOrder, Target: test.archetype}); err == nil || !strings.Contains(err.Error(), test.rejected) {
+			t.Errorf("recruiting %s failed with %v, expected %q", test.archetype, err, test.rejected)
+		}
+	}
+}
+
+func TestStepTraining(t *testing.T) {
+	var (
+		world = newTestWorld()
+		wroat = world.Settlements["Wroat"]
+	)
+
+	wroat.Training = []Recruit{
+		{Name: "First Levy of Wroat", Archetype: "Levy", TurnsLeft: 2},
+		{Name: "Second Levy of Wroat", Archetype: "Levy", Occupied: true, TurnsLeft: 1},
+	}
+
+	world.stepTraining(Element(Division))
+
+	if army, found := world.Armies["Second Levy of Wroat"]; !found {
+		t.Fatalf("levy was not raised once trained")
+	} else if army.Location != "Wroat" || army.Allegiance != "Aundair" || army.HP.Current != 20 || army.HP.Max != 40 {
+		t.Errorf("levy raised at %s for %s with %d / %d HP, expected a levy of Aundair at Wroat at half strength",
+			army.Location, army.Allegiance, army.HP.Current, army.HP.Max)
+	}
+
+	if _, found := world.Armies["First Levy of Wroat"]; found || len(wroat.Training) != 1 {
+		t.Errorf("levy with two turns of training left was raised after one")
+	}
+
+	// Training stops while an enemy is in the settlement
+	world.Armies["First Host"].Allegiance = "Thrane"
+	world.Armies["First Host"].Location = "Wroat"
+
+	if world.stepTraining(Element(Division)); wroat.Training[0].TurnsLeft != 1 {
+		t.Errorf("levy trained with an enemy army in Wroat")
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DieRecord is a single die within a roll. Faces holds every face the die showed, including the
+// face that was rerolled and any extra faces from exploding.
+type DieRecord struct {
+	Faces    []int
+	Value    int
+	Rerolled bool
+	Dropped  bool
+}
+
+func (s DieRecord) String() string {
+	var faces []string
+	for _, face := range s.Faces {
+		faces = append(faces, fmt.Sprintf("%d", face))
+	}
+
+	description := strings.Join(faces, "!")
+	if s.Rerolled {
+		description = fmt.Sprintf("%s→%s", faces[0], strings.Join(faces[1:], "!"))
+	}
+
+	if s.Dropped {
+		return fmt.Sprintf("~%s~", description)
+	}
+
+	return description
+}
+
+// DiceRecord holds the dice rolled for one dice term of an expression such as 4d6kh3. Natural
+// is the raw face of a d20 term that decides critical hits and fumbles, if this is one.
+type DiceRecord struct {
+	Term    string
+	Sides   int
+	Natural int `toml:",omitempty"`
+	Dice    []DieRecord
+}
+
+func (s DiceRecord) String() string {
+	var dice []string
+	for _, die := range s.Dice {
+		dice = append(dice, die.String())
+	}
+
+	return fmt.Sprintf("%s [%s]", s.Term, strings.Join(dice, ", "))
+}
+
+// RollRecord is the audit trail of a single roll. Target is the number the roll needed to meet
+// or beat, if any.
+type RollRecord struct {
+	ID          int
+	Description string
+	Expression  string
+	Terms       []DiceRecord
+	Modifiers   []int
+	Multipliers []int
+	Total       int
+	Target      int  `toml:",omitempty"`
+	Critical    bool `toml:",omitempty"`
+}
+
+// Natural returns the raw face of the d20 that decides whether this roll is a critical hit or a
+// fumble, separate from any modifiers. Returns false if the roll has no such d20.
+func (s *RollRecord) Natural() (int, bool) {
+	for _, term := range s.Terms {
+		if term.Natural > 0 {
+			return term.Natural, true
+		}
+	}
+
+	return 0, false
+}
+
+// subtract rolls an expression whose result will be subtracted, recording its flat numbers as
+// negative modifiers.
+func (s *RollRecord) subtract(expr RollExpr, rng *rand.Rand) int {
+	inner := &RollRecord{}
+	value := expr.Roll(rng, inner)
+
+	s.Terms = append(s.Terms, inner.Terms...)
+	s.Multipliers = append(s.Multipliers, inner.Multipliers...)
+
+	for _, modifier := range inner.Modifiers {
+		s.Modifiers = append(s.Modifiers, -modifier)
+	}
+
+	return value
+}
+
+// multiply rolls one side of a product, recording a flat number as a multiplier.
+func (s *RollRecord) multiply(expr RollExpr, rng *rand.Rand) int {
+	if constant, isConstant := expr.(*Constant); isConstant {
+		s.Multipliers = append(s.Multipliers, constant.Value)
+		return constant.Value
+	}
+
+	return expr.Roll(rng, s)
+}
+
+func (s *RollRecord) String() string {
+	var parts []string
+	for _, dice := range s.Terms {
+		parts = append(parts, dice.String())
+	}
+
+	for _, modifier := range s.Modifiers {
+		parts = append(parts, fmt.Sprintf("%+d", modifier))
+	}
+
+	for _, multiplier := range s.Multipliers {
+		parts = append(parts, fmt.Sprintf("×%d", multiplier))
+	}
+
+	expression := s.Expression
+	if s.Critical {
+		expression = fmt.Sprintf("%s doubled for a critical hit", expression)
+	}
+
+	description := fmt.Sprintf("#%d %s: %s: %s = %d", s.ID, s.Description, expression, strings.Join(parts, " "), s.Total)
+	if natural, found := s.Natural(); found {
+		description = fmt.Sprintf("%s (natural %d)", description, natural)
+	}
+
+	if s.Target > 0 {
+		description = fmt.Sprintf("%s against %d", description, s.Target)
+	}
+
+	return description
+}
+
+// Roller makes every roll for a turn from a single random source and keeps a record of each
+// roll so that the turn may be audited later.
+type Roller struct {
+	rng   *rand.Rand
+	Rolls []*RollRecord
+}
+
+func NewRoller(rng *rand.Rand) *Roller {
+	return &Roller{
+		rng: rng,
+	}
+}
+
+// Roll rolls the spec and records the result. Target is the number the roll must meet or beat,
+// or zero if there is none.
+func (s *Roller) Roll(spec RollSpec, description string, target int) (*RollRecord, error) {
+	if record, err := spec.Roll(s.rng); err != nil {
+		return nil, err
+	} else {
+		record.ID = len(s.Rolls) + 1
+		record.Description = description
+		record.Target = target
+
+		s.Rolls = append(s.Rolls, record)
+		return record, nil
+	}
+}
+
+// RollCritical rolls the spec with its dice doubled and records the result.
+func (s *Roller) RollCritical(spec RollSpec, description string) (*RollRecord, error) {
+	if record, err := spec.RollCritical(s.rng); err != nil {
+		return nil, err
+	} else {
+		record.ID = len(s.Rolls) + 1
+		record.Description = description
+
+		s.Rolls = append(s.Rolls, record)
+		return record, nil
+	}
+}
+
+// RollLog is the structured log of every roll made during a turn, written beside the rendered
+// turn report.
+type RollLog struct {
+	Turn  int
+	Seed  int64
+	Rolls []*RollRecord
+}
+
+func WriteRollLog(path string, log *RollLog) error {
+	if file, err := os.OpenFile(path, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0644); err != nil {
+		return err
+	} else if err := toml.NewEncoder(file).Encode(log); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RollDetails renders an expandable list of the rolls behind a combat log entry.
+func RollDetails(records ...*RollRecord) *DocumentElement {
+	details := Element(Details)
+	details.Element(Summary).Text = "Rolls"
+
+	rollList := details.Element(UnorderedList)
+	rollList.Attributes["style"] = "list-style-type: none; font-family: monospace;"
+
+	for _, record := range records {
+		rollList.Element(ListItem).Text = record.String()
+	}
+
+	return details
+}
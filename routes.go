@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Route is a road between two settlements. Routes may be travelled in either direction and
+// Distance is the number of turns it takes an army to march from one end to the other.
+type Route struct {
+	From     string
+	To       string
+	Distance int
+}
+
+func (s Route) Turns() int {
+	if s.Distance < 1 {
+		return 1
+	}
+
+	return s.Distance
+}
+
+// Connects returns true if the route links the two named settlements in either direction.
+func (s Route) Connects(first, second string) bool {
+	return (s.From == first && s.To == second) || (s.From == second && s.To == first)
+}
+
+// Other returns the settlement at the far end of the route from the one given.
+func (s Route) Other(name string) string {
+	if s.From == name {
+		return s.To
+	}
+
+	return s.From
+}
+
+// Transit tracks an army that is on the road between two settlements.
+type Transit struct {
+	From     string
+	To       string
+	Progress int
+	Distance int
+}
+
+func (s *Transit) Arrived() bool {
+	return s.Progress >= s.Distance
+}
+
+func (s *Transit) String() string {
+	return fmt.Sprintf("In transit from %s to %s (%d / %d turns)", s.From, s.To, s.Progress, s.Distance)
+}
+
+func (s *World) RouteBetween(first, second string) (Route, bool) {
+	for _, route := range s.Routes {
+		if route.Connects(first, second) {
+			return route, true
+		}
+	}
+
+	return Route{}, false
+}
+
+func (s *World) RoutesFrom(name string) []Route {
+	var routes []Route
+	for _, route := range s.Routes {
+		if route.From == name || route.To == name {
+			routes = append(routes, route)
+		}
+	}
+
+	return routes
+}
+
+// blocksArmy returns true if the named settlement is held by a different actor than the army
+// and the army hasn't been ordered to force its way through hostile territory.
+func (s *World) blocksArmy(name string, army *Army) bool {
+	if army.ForceMarch {
+		return false
+	}
+
+	settlement, found := s.Settlements[name]
+	return found && settlement.Allegiance != army.Allegiance
+}
+
+// PlanRoute finds the quickest path along the road network from the army's location to its
+// destination. The returned path lists each settlement the army must reach in order, ending with
+// the destination itself. Enemy-held settlements may be the destination but are not marched
+// through unless the army is force marching.
+func (s *World) PlanRoute(army *Army) ([]string, error) {
+	var (
+		origin    = army.Location
+		distances = map[string]int{origin: 0}
+		previous  = make(map[string]string)
+		visited   = make(map[string]bool)
+	)
+
+	for {
+		// Pick the closest settlement we haven't visited yet, breaking ties by name so that
+		// planning is stable from turn to turn
+		current, found := "", false
+		for name, distance := range distances {
+			if visited[name] {
+				continue
+			}
+
+			if !found || distance < distances[current] || (distance == distances[current] && name < current) {
+				current = name
+				found = true
+			}
+		}
+
+		if !found || current == army.Destination {
+			break
+		}
+
+		visited[current] = true
+
+		// Armies may reach a blocking settlement but not pass through it
+		if current != origin && s.blocksArmy(current, army) {
+			continue
+		}
+
+		for _, route := range s.RoutesFrom(current) {
+			next := route.Other(current)
+			if visited[next] {
+				continue
+			}
+
+			candidate := distances[current] + route.Turns()
+			if distance, seen := distances[next]; !seen || candidate < distance {
+				distances[next] = candidate
+				previous[next] = current
+			}
+		}
+	}
+
+	if _, reached := distances[army.Destination]; !reached {
+		return nil, fmt.Errorf("no route from %s to %s", origin, army.Destination)
+	}
+
+	var path []string
+	for stop := army.Destination; stop != origin; stop = previous[stop] {
+		path = append([]string{stop}, path...)
+	}
+
+	return path, nil
+}
+
+// moveArmy advances an army one turn along its planned route towards its destination. Armies
+// that are not already on the road will plan a route and set out from their current location.
+// Returns false if the army was unable to move.
+func (s *World) moveArmy(army *Army, actionList *DocumentElement) (bool, error) {
+	if army.Transit == nil {
+		// Plan the route again each time the army sets out since settlements along the way may
+		// have changed hands
+		if path, err := s.PlanRoute(army); err != nil {
+			actionList.Element(ListItem).Text = fmt.Sprintf("Army %s can find no route from %s to %s and holds its position.",
+				NameLink(army.Name), army.Location, army.Destination)
+
+			army.Route = nil
+			return false, nil
+		} else if route, found := s.RouteBetween(army.Location, path[0]); !found {
+			return false, fmt.Errorf("planned route for army %s uses a missing road from %s to %s", army.Name, army.Location, path[0])
+		} else {
+			army.Route = path
+			army.Transit = &Transit{
+				From:     army.Location,
+				To:       path[0],
+				Distance: route.Turns(),
+			}
+
+			// Armies on the road are not at any settlement
+			army.Location = ""
+		}
+	}
+
+	if army.Transit.Progress++; army.Transit.Arrived() {
+		actionList.Element(ListItem).Text = fmt.Sprintf("Army %s has arrived at %s from %s.",
+			NameLink(army.Name), NameLink(army.Transit.To), army.Transit.From)
+
+		army.Location = army.Transit.To
+		army.Transit = nil
+
+		if len(army.Route) > 0 && army.Route[0] == army.Location {
+			army.Route = army.Route[1:]
+		}
+
+		if len(army.Route) == 0 {
+			army.Route = nil
+		}
+	} else {
+		actionList.Element(ListItem).Text = fmt.Sprintf("Army %s is travelling from %s to %s (%d / %d turns).",
+			NameLink(army.Name), army.Transit.From, army.Transit.To, army.Transit.Progress, army.Transit.Distance)
+	}
+
+	return true, nil
+}
+
+// RemainingRoute describes the settlements an army has yet to reach on its way to its destination.
+func (s *Army) RemainingRoute() string {
+	if len(s.Route) == 0 {
+		return "None"
+	}
+
+	return strings.Join(s.Route, " → ")
+}
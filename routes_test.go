@@ -0,0 +1,176 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// newTestWorld builds a small world of Aundair settlements along a road, with Thrane holding
+// Olath at the end of it. An Aundair army waits at Aelyndar.
+//
+//	Aelyndar -2- Thaliost -1- Wroat -1- Olath
+//	    \______________4_______/
+func newTestWorld() *World {
+	world := NewWorld()
+
+	for _, actor := range []string{"Aundair", "Thrane"} {
+		world.Actors[actor] = &WorldActor{Name: actor, Treasury: 100, Food: 100}
+	}
+
+	for name, allegiance := range map[string]string{
+		"Aelyndar": "Aundair",
+		"Thaliost": "Aundair",
+		"Wroat":    "Aundair",
+		"Olath":    "Thrane",
+	} {
+		world.Settlements[name] = &Settlement{
+			Name:       name,
+			HP:         &HealthTracker{Current: 100, Max: 100},
+			DamageRoll: RollSpec{Die("d6")},
+			Allegiance: allegiance,
+			Population: 1000,
+		}
+	}
+
+	world.Routes = []Route{
+		{From: "Aelyndar", To: "Thaliost", Distance: 2},
+		{From: "Thaliost", To: "Wroat", Distance: 1},
+		{From: "Wroat", To: "Olath", Distance: 1},
+		{From: "Aelyndar", To: "Wroat", Distance: 4},
+	}
+
+	world.Armies["First Host"] = &Army{
+		Name:        "First Host",
+		HP:          &HealthTracker{Current: 50, Max: 50},
+		AC:          12,
+		AttackRoll:  RollSpec{Die("d20+4")},
+		DamageRoll:  RollSpec{Die("2d6")},
+		Location:    "Aelyndar",
+		Destination: "Aelyndar",
+		Allegiance:  "Aundair",
+		Morale:      maxMorale,
+	}
+
+	return world
+}
+
+func TestRouteBetween(t *testing.T) {
+	world := newTestWorld()
+
+	if route, found := world.RouteBetween("Thaliost", "Aelyndar"); !found {
+		t.Errorf("no road found from Thaliost back to Aelyndar")
+	} else if route.Turns() != 2 {
+		t.Errorf("road from Aelyndar to Thaliost takes %d turns, expected 2", route.Turns())
+	}
+
+	if _, found := world.RouteBetween("Aelyndar", "Olath"); found {
+		t.Errorf("found a road from Aelyndar to Olath, expected none")
+	}
+
+	if turns := (Route{From: "Wroat", To: "Olath"}).Turns(); turns != 1 {
+		t.Errorf("road without a distance takes %d turns, expected 1", turns)
+	}
+}
+
+func TestMoveArmy(t *testing.T) {
+	var (
+		world = newTestWorld()
+		army  = world.Armies["First Host"]
+	)
+
+	army.Destination = "Thaliost"
+
+	if moved, err := world.moveArmy(army, Element(UnorderedList)); err != nil || !moved {
+		t.Fatalf("army failed to set out: %v", err)
+	} else if army.Location != "" || !army.InTransit() {
+		t.Fatalf("army is at %q after setting out, expected it to be on the road", army.Location)
+	} else if expected := (Transit{From: "Aelyndar", To: "Thaliost", Progress: 1, Distance: 2}); *army.Transit != expected {
+		t.Fatalf("army is %s, expected %s", army.Transit, &expected)
+	}
+
+	if moved, err := world.moveArmy(army, Element(UnorderedList)); err != nil || !moved {
+		t.Fatalf("army failed to finish its march: %v", err)
+	} else if army.Location != "Thaliost" || army.InTransit() {
+		t.Errorf("army is at %q after two turns on the road, expected it to arrive at Thaliost", army.Location)
+	} else if army.Route != nil {
+		t.Errorf("army still has %s left to march after arriving", army.RemainingRoute())
+	}
+}
+
+func TestPlanRoute(t *testing.T) {
+	tests := []struct {
+		description string
+		destination string
+		thrane      []string
+		forceMarch  bool
+		expected    []string
+	}{
+		{
+			description: "take the quicker road through Thaliost",
+			destination: "Wroat",
+			expected:    []string{"Thaliost", "Wroat"},
+		},
+		{
+			description: "march on an enemy-held destination",
+			destination: "Olath",
+			expected:    []string{"Thaliost", "Wroat", "Olath"},
+		},
+		{
+			description: "go around an enemy-held settlement",
+			destination: "Wroat",
+			thrane:      []string{"Thaliost"},
+			expected:    []string{"Wroat"},
+		},
+		{
+			description: "force march through an enemy-held settlement",
+			destination: "Wroat",
+			thrane:      []string{"Thaliost"},
+			forceMarch:  true,
+			expected:    []string{"Thaliost", "Wroat"},
+		},
+		{
+			description: "find no way past an enemy-held settlement",
+			destination: "Olath",
+			thrane:      []string{"Wroat"},
+		},
+	}
+
+	for _, test := range tests {
+		var (
+			world = newTestWorld()
+			army  = world.Armies["First Host"]
+		)
+
+		for _, name := range test.thrane {
+			world.Settlements[name].Allegiance = "Thrane"
+		}
+
+		army.Destination = test.destination
+		army.ForceMarch = test.forceMarch
+
+		if path, err := world.PlanRoute(army); test.expected == nil && err == nil {
+			t.Errorf("%s: planned %v, expected no route", test.description, path)
+		} else if test.expected != nil && err != nil {
+			t.Errorf("%s: %v", test.description, err)
+		} else if !reflect.DeepEqual(path, test.expected) {
+			t.Errorf("%s: planned %v, expected %v", test.description, path, test.expected)
+		}
+	}
+}
+
+func TestMoveArmyWithoutRoute(t *testing.T) {
+	var (
+		world = newTestWorld()
+		army  = world.Armies["First Host"]
+	)
+
+	world.Settlements["Wroat"].Allegiance = "Thrane"
+	army.Destination = "Olath"
+	army.Route = []string{"Thaliost", "Wroat", "Olath"}
+
+	if moved, err := world.moveArmy(army, Element(UnorderedList)); err != nil || moved {
+		t.Errorf("army with no route moved (%v), expected it to hold its position", err)
+	} else if army.Location != "Aelyndar" || army.Route != nil {
+		t.Errorf("army is at %q with route %s, expected it to stay at Aelyndar with none", army.Location, army.RemainingRoute())
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+)
+
+const (
+	defaultScenarioDir = "scenarios"
+
+	PlacementCapital = "capital"
+	PlacementSpread  = "spread"
+)
+
+// SettlementArchetype holds what a kind of settlement, such as a keep or a village, usually has.
+// Unless a settlement's HP is given it is worked out from the settlement's population.
+type SettlementArchetype struct {
+	PopulationPerHP uint
+	HP              int
+	DamageRoll      RollSpec
+	HasWarGuard     bool
+	Fortifications  []string
+}
+
+// ArmyArchetype holds the usual statistics for a kind of army.
+type ArmyArchetype struct {
+	HP           int
+	AC           int
+	AttackRoll   RollSpec
+	DamageRoll   RollSpec
+	SiegeEngines bool
+	Surgeons     bool
+}
+
+// ScenarioActor describes where an actor's armies are placed when the scenario doesn't say.
+// Placement is either capital, putting them all in the actor's capital, or spread, sharing them
+// out between the actor's settlements from the most populous down. Actors with a capital place
+// their armies there by default. Treasury, Food and Materials are what the actor starts with.
+type ScenarioActor struct {
+	Capital   string
+	Placement string
+	Treasury  int
+	Food      int
+	Materials int
+}
+
+// ScenarioSettlement is a settlement built from an archetype. Any value given here replaces the
+// archetype's. CurrentHP starts the settlement damaged.
+type ScenarioSettlement struct {
+	Name           string
+	Archetype      string
+	Allegiance     string
+	Population     uint
+	Occupied       bool
+	HP             int
+	CurrentHP      int
+	DamageRoll     RollSpec
+	HasWarGuard    *bool
+	Fortifications []string
+}
+
+// ScenarioArmy is an army built from an archetype. Any value given here replaces the
+// archetype's. Armies without a location are placed by their actor's placement rule.
+type ScenarioArmy struct {
+	Name         string
+	Archetype    string
+	Allegiance   string
+	Location     string
+	HP           int
+	CurrentHP    int
+	AC           int
+	AttackRoll   RollSpec
+	DamageRoll   RollSpec
+	SiegeEngines *bool
+	Surgeons     *bool
+}
+
+// Scenario describes step 0 of a simulation in terms of archetypes, so that a scenario only
+// needs to spell out what makes each settlement and army different. Fortifications are looked
+// up by their key, and take the key as their name unless they are given one. Fortifications
+// without HP are given the default for their defense modifier.
+type Scenario struct {
+	SchemaVersion int
+
+	Name                 string
+	Fortifications       map[string]Fortification
+	SettlementArchetypes map[string]SettlementArchetype
+	ArmyArchetypes       map[string]ArmyArchetype
+	Actors               map[string]*ScenarioActor
+	Settlements          []ScenarioSettlement
+	Armies               []ScenarioArmy
+	Routes               []Route
+	Rules                *Rules
+}
+
+// scenarioMigrations upgrade older scenario files, as worldMigrations do for worlds.
+var scenarioMigrations []Migration
+
+func LoadScenario(path string) (*Scenario, error) {
+	scenario := &Scenario{}
+	if err := decodeVersioned(path, scenarioMigrations, scenario); err != nil {
+		return nil, err
+	}
+
+	return scenario, nil
+}
+
+// FindScenario returns the path of a scenario, which is either a file or the name of one of
+// the scenarios in the scenario directory.
+func FindScenario(scenarioDir, scenario string) (string, error) {
+	if _, err := os.Stat(scenario); err == nil {
+		return scenario, nil
+	}
+
+	scenarioPath := path.Join(scenarioDir, fmt.Sprintf("%s.toml", DocumentID(scenario)))
+	if _, err := os.Stat(scenarioPath); err != nil {
+		return "", fmt.Errorf("there is no scenario file or scenario named %s in %s", scenario, scenarioDir)
+	}
+
+	return scenarioPath, nil
+}
+
+func (s *Scenario) fortifications(names []string) ([]Fortification, error) {
+	var fortifications []Fortification
+	for _, name := range names {
+		if fortification, found := s.Fortifications[name]; !found {
+			return nil, fmt.Errorf("there is no fortification named %s", name)
+		} else {
+			if len(fortification.Name) == 0 {
+				fortification.Name = name
+			}
+
+			// Every settlement gets its own HP for the fortification so that damage to one
+			// settlement's walls isn't shared with the rest
+			maxHP := DefaultFortificationHP(fortification.DefenseModifier)
+			if fortification.HP != nil {
+				maxHP = fortification.HP.Max
+			}
+
+			fortification.HP = &HealthTracker{
+				Current: maxHP,
+				Max:     maxHP,
+			}
+
+			fortifications = append(fortifications, fortification)
+		}
+	}
+
+	return fortifications, nil
+}
+
+func (s *Scenario) buildSettlement(template ScenarioSettlement) (*Settlement, error) {
+	archetype, found := s.SettlementArchetypes[template.Archetype]
+	if !found {
+		return nil, fmt.Errorf("settlement %s: there is no settlement archetype named %s", template.Name, template.Archetype)
+	}
+
+	settlement := &Settlement{
+		Name:        template.Name,
+		DamageRoll:  archetype.DamageRoll,
+		HasWarGuard: archetype.HasWarGuard,
+		Allegiance:  template.Allegiance,
+		Occupied:    template.Occupied,
+		Population:  template.Population,
+	}
+
+	maxHP := archetype.HP
+	if template.HP > 0 {
+		maxHP = template.HP
+	} else if archetype.PopulationPerHP > 0 {
+		maxHP = int(template.Population / archetype.PopulationPerHP)
+	}
+
+	settlement.HP = &HealthTracker{
+		Current: maxHP,
+		Max:     maxHP,
+	}
+
+	if template.CurrentHP > 0 {
+		settlement.HP.Current = template.CurrentHP
+	}
+
+	if len(template.DamageRoll) > 0 {
+		settlement.DamageRoll = template.DamageRoll
+	}
+
+	if template.HasWarGuard != nil {
+		settlement.HasWarGuard = *template.HasWarGuard
+	}
+
+	fortificationNames := archetype.Fortifications
+	if template.Fortifications != nil {
+		fortificationNames = template.Fortifications
+	}
+
+	if fortifications, err := s.fortifications(fortificationNames); err != nil {
+		return nil, fmt.Errorf("settlement %s: %v", template.Name, err)
+	} else {
+		settlement.Fortifications = fortifications
+	}
+
+	return settlement, nil
+}
+
+func (s *Scenario) buildArmy(template ScenarioArmy) (*Army, error) {
+	archetype, found := s.ArmyArchetypes[template.Archetype]
+	if !found {
+		return nil, fmt.Errorf("army %s: there is no army archetype named %s", template.Name, template.Archetype)
+	}
+
+	army := &Army{
+		Name:         template.Name,
+		AC:           archetype.AC,
+		AttackRoll:   archetype.AttackRoll,
+		DamageRoll:   archetype.DamageRoll,
+		Location:     template.Location,
+		Destination:  template.Location,
+		Allegiance:   template.Allegiance,
+		SiegeEngines: archetype.SiegeEngines,
+		Surgeons:     archetype.Surgeons,
+		Morale:       maxMorale,
+	}
+
+	maxHP := archetype.HP
+	if template.HP > 0 {
+		maxHP = template.HP
+	}
+
+	army.HP = &HealthTracker{
+		Current: maxHP,
+		Max:     maxHP,
+	}
+
+	if template.CurrentHP > 0 {
+		army.HP.Current = template.CurrentHP
+	}
+
+	if template.AC > 0 {
+		army.AC = template.AC
+	}
+
+	if len(template.AttackRoll) > 0 {
+		army.AttackRoll = template.AttackRoll
+	}
+
+	if len(template.DamageRoll) > 0 {
+		army.DamageRoll = template.DamageRoll
+	}
+
+	if template.SiegeEngines != nil {
+		army.SiegeEngines = *template.SiegeEngines
+	}
+
+	if template.Surgeons != nil {
+		army.Surgeons = *template.Surgeons
+	}
+
+	return army, nil
+}
+
+// placeArmies finds a location for every army that the scenario didn't give one.
+func (s *Scenario) placeArmies(world *World) error {
+	placed := make(map[string]int)
+
+	for _, army := range ArmyListFromMap(world.Armies).Sorted() {
+		if len(army.Location) > 0 {
+			continue
+		}
+
+		actor, found := s.Actors[army.Allegiance]
+		if !found {
+			return fmt.Errorf("army %s: there is no actor named %s to place it", army.Name, army.Allegiance)
+		}
+
+		placement := actor.Placement
+		if len(placement) == 0 {
+			if placement = PlacementSpread; len(actor.Capital) > 0 {
+				placement = PlacementCapital
+			}
+		}
+
+		switch placement {
+		case PlacementCapital:
+			if _, found := world.Settlements[actor.Capital]; !found {
+				return fmt.Errorf("army %s: %s has no capital to place it in", army.Name, army.Allegiance)
+			}
+
+			army.Location = actor.Capital
+
+		case PlacementSpread:
+			strongholds := world.SettlementsByActor()[army.Allegiance]
+			if len(strongholds) == 0 {
+				return fmt.Errorf("army %s: %s has no settlements to place it in", army.Name, army.Allegiance)
+			}
+
+			sort.Slice(strongholds, func(i, j int) bool {
+				if strongholds[i].Population != strongholds[j].Population {
+					return strongholds[i].Population > strongholds[j].Population
+				}
+
+				return strongholds[i].Name < strongholds[j].Name
+			})
+
+			army.Location = strongholds[placed[army.Allegiance]%len(strongholds)].Name
+			placed[army.Allegiance]++
+
+		default:
+			return fmt.Errorf("actor %s: unknown placement %s, expected capital or spread", army.Allegiance, placement)
+		}
+
+		army.Destination = army.Location
+	}
+
+	return nil
+}
+
+// Build expands the scenario into the world for step 0 of a simulation.
+func (s *Scenario) Build() (*World, error) {
+	world := NewWorld()
+	world.Routes = s.Routes
+	world.Rules = s.Rules
+
+	for name, actor := range s.Actors {
+		world.Actors[name] = &WorldActor{
+			Name:      name,
+			Treasury:  actor.Treasury,
+			Food:      actor.Food,
+			Materials: actor.Materials,
+		}
+	}
+
+	for _, template := range s.Settlements {
+		if _, found := world.Settlements[template.Name]; found {
+			return nil, fmt.Errorf("settlement %s is listed twice", template.Name)
+		} else if settlement, err := s.buildSettlement(template); err != nil {
+			return nil, err
+		} else {
+			world.Settlements[settlement.Name] = settlement
+		}
+	}
+
+	for _, template := range s.Armies {
+		if _, found := world.Armies[template.Name]; found {
+			return nil, fmt.Errorf("army %s is listed twice", template.Name)
+		} else if army, err := s.buildArmy(template); err != nil {
+			return nil, err
+		} else {
+			world.Armies[army.Name] = army
+		}
+	}
+
+	if err := s.placeArmies(world); err != nil {
+		return nil, err
+	}
+
+	return world, nil
+}
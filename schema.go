@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	schemaVersionKey = "SchemaVersion"
+
+	// Actors in worlds written before the economy are given this many turns of their armies'
+	// upkeep to start with
+	legacyStockpileTurns = 20
+)
+
+var bareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Migration upgrades a decoded TOML document from one schema version to the next.
+type Migration struct {
+	Description string
+	Migrate     func(document map[string]interface{}) error
+}
+
+// The migrations for each kind of file, in order. A file at schema version N is upgraded by
+// running every migration from index N onwards, so the current version of each schema is the
+// number of migrations it has. Files written before schema versions were recorded are at
+// version 0.
+var (
+	worldMigrations = []Migration{
+		{
+			Description: "drop the attack roll from settlements, which is now worked out from their fortifications",
+			Migrate: func(document map[string]interface{}) error {
+				for _, settlement := range tables(document, "Settlements") {
+					delete(settlement, "AttackRoll")
+				}
+
+				return nil
+			},
+		},
+		{
+			Description: "give fortifications HP, with breached fortifications at 0",
+			Migrate: func(document map[string]interface{}) error {
+				for _, settlement := range tables(document, "Settlements") {
+					fortifications, _ := settlement["Fortifications"].([]map[string]interface{})
+					for _, fortification := range fortifications {
+						if _, found := fortification["HP"]; found {
+							continue
+						}
+
+						defenseModifier, _ := fortification["DefenseModifier"].(int64)
+						maxHP := int64(DefaultFortificationHP(int(defenseModifier)))
+
+						currentHP := maxHP
+						if breached, _ := fortification["Breached"].(bool); breached {
+							currentHP = 0
+						}
+
+						fortification["HP"] = map[string]interface{}{
+							"Current": currentHP,
+							"Max":     maxHP,
+						}
+					}
+				}
+
+				return nil
+			},
+		},
+		{
+			Description: "give the surgeon trait to armies of surgeons",
+			Migrate: func(document map[string]interface{}) error {
+				for _, army := range tables(document, "Armies") {
+					if name, _ := army["Name"].(string); strings.HasSuffix(name, "Surgeons") {
+						army["Surgeons"] = true
+					}
+				}
+
+				return nil
+			},
+		},
+		{
+			Description: "start every army at full morale",
+			Migrate: func(document map[string]interface{}) error {
+				for _, army := range tables(document, "Armies") {
+					if _, found := army["Morale"]; !found {
+						army["Morale"] = int64(maxMorale)
+					}
+				}
+
+				return nil
+			},
+		},
+		{
+			Description: "replace the besiege flag with the assault stance",
+			Migrate: func(document map[string]interface{}) error {
+				for _, army := range tables(document, "Armies") {
+					if besiege, _ := army["Besiege"].(bool); besiege {
+						army["Stance"] = string(StanceAssault)
+					}
+
+					delete(army, "Besiege")
+				}
+
+				return nil
+			},
+		},
+		{
+			Description: "give actors without stockpiles enough gold and food to keep their armies in the field for a while",
+			Migrate: func(document map[string]interface{}) error {
+				upkeep := make(map[string]int64)
+				for _, army := range tables(document, "Armies") {
+					if destroyed, _ := army["Destroyed"].(bool); destroyed {
+						continue
+					}
+
+					var (
+						allegiance, _ = army["Allegiance"].(string)
+						hp, _         = army["HP"].(map[string]interface{})
+						maxHP, _      = hp["Max"].(int64)
+					)
+
+					upkeep[allegiance] += int64(defaultEconomy.Upkeep(&Army{HP: &HealthTracker{Max: int(maxHP)}}).Gold)
+				}
+
+				for _, actor := range tables(document, "Actors") {
+					name, _ := actor["Name"].(string)
+					stockpile := upkeep[name] * legacyStockpileTurns
+
+					for _, key := range []string{"Treasury", "Food"} {
+						if _, found := actor[key]; !found {
+							actor[key] = stockpile
+						}
+					}
+				}
+
+				return nil
+			},
+		},
+	}
+
+	simulationMigrations = []Migration{
+		{
+			Description: "record the schema version",
+			Migrate: func(document map[string]interface{}) error {
+				return nil
+			},
+		},
+		{
+			Description: "give simulations started before seeds were recorded a seed of their own",
+			Migrate: func(document map[string]interface{}) error {
+				if _, found := document["Seed"]; found {
+					return nil
+				}
+
+				// The seed comes from the simulation's name so that every command reading the
+				// old state file agrees on it, whether or not it writes the state back
+				name, _ := document["Name"].(string)
+				hash := fnv.New64a()
+				hash.Write([]byte(name))
+
+				document["Seed"] = int64(hash.Sum64())
+				return nil
+			},
+		},
+	}
+)
+
+var (
+	WorldSchemaVersion      = len(worldMigrations)
+	SimulationSchemaVersion = len(simulationMigrations)
+)
+
+// tables returns the tables held under a key of a document, such as each settlement under
+// Settlements.
+func tables(document map[string]interface{}, key string) []map[string]interface{} {
+	var found []map[string]interface{}
+
+	if parent, isTable := document[key].(map[string]interface{}); isTable {
+		for _, value := range parent {
+			if table, isTable := value.(map[string]interface{}); isTable {
+				found = append(found, table)
+			}
+		}
+	}
+
+	return found
+}
+
+// formatKey formats a TOML key path, quoting the parts that need it.
+func formatKey(key toml.Key) string {
+	var parts []string
+	for _, part := range key {
+		if bareKeyPattern.MatchString(part) {
+			parts = append(parts, part)
+		} else {
+			parts = append(parts, fmt.Sprintf("%q", part))
+		}
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// decodeVersioned reads a TOML file, upgrades it to the current schema version using the given
+// migrations and decodes it into value. Any key in the file that value has no place for is
+// reported as an error rather than silently dropped.
+func decodeVersioned(path string, migrations []Migration, value interface{}) error {
+	document := make(map[string]interface{})
+	if _, err := toml.DecodeFile(path, &document); err != nil {
+		return err
+	}
+
+	version := 0
+	if rawVersion, found := document[schemaVersionKey]; found {
+		if decodedVersion, isInteger := rawVersion.(int64); !isInteger {
+			return fmt.Errorf("%s: %s must be a whole number", path, schemaVersionKey)
+		} else {
+			version = int(decodedVersion)
+		}
+	}
+
+	if version < 0 || version > len(migrations) {
+		return fmt.Errorf("%s: schema version %d is not supported, the latest is %d", path, version, len(migrations))
+	}
+
+	for ; version < len(migrations); version++ {
+		if err := migrations[version].Migrate(document); err != nil {
+			return fmt.Errorf("%s: unable to %s: %v", path, migrations[version].Description, err)
+		}
+	}
+
+	document[schemaVersionKey] = int64(len(migrations))
+
+	// Write the upgraded document back out so that it may be decoded into the struct
+	buffer := &bytes.Buffer{}
+	if err := toml.NewEncoder(buffer).Encode(document); err != nil {
+		return err
+	}
+
+	metadata, err := toml.Decode(buffer.String(), value)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+		var keys []string
+		for _, key := range undecoded {
+			keys = append(keys, formatKey(key))
+		}
+
+		return fmt.Errorf("%s: unknown keys:\n  %s", path, strings.Join(keys, "\n  "))
+	}
+
+	return nil
+}
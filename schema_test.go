@@ -0,0 +1,181 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWorldMigrations(t *testing.T) {
+	tests := []struct {
+		description string
+		document    map[string]interface{}
+		expected    map[string]interface{}
+	}{
+		{
+			description: "drop the attack roll from settlements",
+			document: map[string]interface{}{
+				"Settlements": map[string]interface{}{
+					"Aelyndar": map[string]interface{}{"Name": "Aelyndar", "AttackRoll": []interface{}{"d20"}},
+				},
+			},
+			expected: map[string]interface{}{
+				"Settlements": map[string]interface{}{
+					"Aelyndar": map[string]interface{}{"Name": "Aelyndar"},
+				},
+			},
+		},
+		{
+			description: "give fortifications HP",
+			document: map[string]interface{}{
+				"Settlements": map[string]interface{}{
+					"Aelyndar": map[string]interface{}{
+						"Fortifications": []map[string]interface{}{
+							{"Name": "Wooden Walls", "DefenseModifier": int64(10)},
+							{"Name": "Wooden Battlements", "DefenseModifier": int64(4), "Breached": true},
+						},
+					},
+				},
+			},
+			expected: map[string]interface{}{
+				"Settlements": map[string]interface{}{
+					"Aelyndar": map[string]interface{}{
+						"Fortifications": []map[string]interface{}{
+							{"Name": "Wooden Walls", "DefenseModifier": int64(10), "HP": map[string]interface{}{"Current": int64(100), "Max": int64(100)}},
+							{"Name": "Wooden Battlements", "DefenseModifier": int64(4), "Breached": true, "HP": map[string]interface{}{"Current": int64(0), "Max": int64(40)}},
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "give the surgeon trait to armies of surgeons",
+			document: map[string]interface{}{
+				"Armies": map[string]interface{}{
+					"Surgeons":  map[string]interface{}{"Name": "Field Surgeons"},
+					"First Cog": map[string]interface{}{"Name": "First Cog"},
+				},
+			},
+			expected: map[string]interface{}{
+				"Armies": map[string]interface{}{
+					"Surgeons":  map[string]interface{}{"Name": "Field Surgeons", "Surgeons": true},
+					"First Cog": map[string]interface{}{"Name": "First Cog"},
+				},
+			},
+		},
+		{
+			description: "start every army at full morale",
+			document: map[string]interface{}{
+				"Armies": map[string]interface{}{
+					"Fresh":  map[string]interface{}{"Name": "Fresh"},
+					"Shaken": map[string]interface{}{"Name": "Shaken", "Morale": int64(30)},
+				},
+			},
+			expected: map[string]interface{}{
+				"Armies": map[string]interface{}{
+					"Fresh":  map[string]interface{}{"Name": "Fresh", "Morale": int64(maxMorale)},
+					"Shaken": map[string]interface{}{"Name": "Shaken", "Morale": int64(30)},
+				},
+			},
+		},
+		{
+			description: "replace the besiege flag with the assault stance",
+			document: map[string]interface{}{
+				"Armies": map[string]interface{}{
+					"Besieging": map[string]interface{}{"Name": "Besieging", "Besiege": true},
+					"Marching":  map[string]interface{}{"Name": "Marching", "Besiege": false},
+				},
+			},
+			expected: map[string]interface{}{
+				"Armies": map[string]interface{}{
+					"Besieging": map[string]interface{}{"Name": "Besieging", "Stance": "assault"},
+					"Marching":  map[string]interface{}{"Name": "Marching"},
+				},
+			},
+		},
+		{
+			description: "give actors without stockpiles gold and food",
+			document: map[string]interface{}{
+				"Armies": map[string]interface{}{
+					"Cog":    map[string]interface{}{"Allegiance": "Mechanist", "HP": map[string]interface{}{"Max": int64(50)}},
+					"Wreck":  map[string]interface{}{"Allegiance": "Mechanist", "Destroyed": true, "HP": map[string]interface{}{"Max": int64(80)}},
+					"Knight": map[string]interface{}{"Allegiance": "Thrane", "HP": map[string]interface{}{"Max": int64(40)}},
+				},
+				"Actors": map[string]interface{}{
+					"Mechanist": map[string]interface{}{"Name": "Mechanist"},
+					"Thrane":    map[string]interface{}{"Name": "Thrane", "Treasury": int64(7), "Food": int64(3)},
+				},
+			},
+			expected: map[string]interface{}{
+				"Armies": map[string]interface{}{
+					"Cog":    map[string]interface{}{"Allegiance": "Mechanist", "HP": map[string]interface{}{"Max": int64(50)}},
+					"Wreck":  map[string]interface{}{"Allegiance": "Mechanist", "Destroyed": true, "HP": map[string]interface{}{"Max": int64(80)}},
+					"Knight": map[string]interface{}{"Allegiance": "Thrane", "HP": map[string]interface{}{"Max": int64(40)}},
+				},
+				"Actors": map[string]interface{}{
+					"Mechanist": map[string]interface{}{"Name": "Mechanist", "Treasury": int64(5 * legacyStockpileTurns), "Food": int64(5 * legacyStockpileTurns)},
+					"Thrane":    map[string]interface{}{"Name": "Thrane", "Treasury": int64(7), "Food": int64(3)},
+				},
+			},
+		},
+	}
+
+	if len(tests) != len(worldMigrations) {
+		t.Fatalf("%d world migrations are tested but there are %d", len(tests), len(worldMigrations))
+	}
+
+	for idx, test := range tests {
+		if err := worldMigrations[idx].Migrate(test.document); err != nil {
+			t.Errorf("migration %d, %s, failed: %v", idx, test.description, err)
+		} else if !reflect.DeepEqual(test.document, test.expected) {
+			t.Errorf("migration %d, %s, produced\n  %v\nexpected\n  %v", idx, test.description, test.document, test.expected)
+		}
+	}
+}
+
+func TestSimulationMigrationsSeedLegacySimulations(t *testing.T) {
+	var (
+		legacy = map[string]interface{}{"Name": "Siege of Thrane", "Step": int64(0)}
+		reread = map[string]interface{}{"Name": "Siege of Thrane", "Step": int64(0)}
+		other  = map[string]interface{}{"Name": "Fall of Olath", "Step": int64(0)}
+		seeded = map[string]interface{}{"Name": "Siege of Thrane", "Step": int64(0), "Seed": int64(42)}
+	)
+
+	for _, document := range []map[string]interface{}{legacy, reread, other, seeded} {
+		for idx, migration := range simulationMigrations {
+			if err := migration.Migrate(document); err != nil {
+				t.Fatalf("migration %d, %s, failed: %v", idx, migration.Description, err)
+			}
+		}
+	}
+
+	if seed, _ := legacy["Seed"].(int64); seed == 0 {
+		t.Errorf("legacy simulation was not given a seed")
+	} else if legacy["Seed"] != reread["Seed"] {
+		t.Errorf("legacy simulation was given seed %v and then %v, expected the same seed each time it is read", legacy["Seed"], reread["Seed"])
+	} else if legacy["Seed"] == other["Seed"] {
+		t.Errorf("legacy simulations with different names were both given seed %v", legacy["Seed"])
+	}
+
+	if seed, _ := seeded["Seed"].(int64); seed != 42 {
+		t.Errorf("simulation seeded with 42 now has seed %d", seed)
+	}
+}
+
+func TestLoadLegacyWorld(t *testing.T) {
+	world, err := LoadWorld("state/siege_of_thrane.0.toml")
+	if err != nil {
+		t.Fatalf("failed to load the legacy world: %v", err)
+	}
+
+	for _, actor := range world.SortedActors() {
+		if actor.Treasury <= 0 || actor.Food <= 0 {
+			t.Errorf("actor %s starts with %s, expected a stockpile", actor.Name, actor.Holdings())
+		}
+	}
+
+	for _, army := range world.Armies {
+		if army.Morale != maxMorale {
+			t.Errorf("army %s starts with %d morale, expected %d", army.Name, army.Morale, maxMorale)
+		}
+	}
+}
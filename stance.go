@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+)
+
+const (
+	siegeHPPercent         = 5
+	siegePopulationPercent = 2
+)
+
+// Stance is how an army fights wherever it is. Armies without a stance fight any enemy armies
+// they find and then the settlement itself.
+type Stance string
+
+const (
+	// Assaulting armies ignore the defenders and attack the walls directly
+	StanceAssault Stance = "assault"
+
+	// Besieging armies make no attacks, instead starving the settlement of its HP and people
+	// each turn while staying out of reach of its walls
+	StanceSiege Stance = "siege"
+
+	// Holding armies defend themselves but never attack
+	StanceHold Stance = "hold"
+
+	// Skirmishing armies only engage other armies and leave the settlement alone
+	StanceSkirmish Stance = "skirmish"
+)
+
+var stances = []Stance{StanceAssault, StanceSiege, StanceHold, StanceSkirmish}
+
+// Known returns true if the stance is one of the stances an army may take, or no stance.
+func (s Stance) Known() bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	for _, stance := range stances {
+		if s == stance {
+			return true
+		}
+	}
+
+	return false
+}
+
+// EngagesArmies returns true if armies with the stance attack the enemy armies they find.
+func (s Stance) EngagesArmies() bool {
+	return s != StanceAssault && s != StanceSiege && s != StanceHold
+}
+
+// EngagesSettlement returns true if armies with the stance attack an enemy settlement's walls.
+func (s Stance) EngagesSettlement() bool {
+	return s != StanceSiege && s != StanceHold && s != StanceSkirmish
+}
+
+// Besiegers returns the enemy armies laying siege to the settlement.
+func (s *World) Besiegers(settlement *Settlement) ArmyList {
+	var besiegers ArmyList
+	for _, army := range s.EnemiesAt(settlement.Name, settlement.Allegiance) {
+		if army.Stance == StanceSiege && !army.Routed {
+			besiegers = append(besiegers, army)
+		}
+	}
+
+	return besiegers
+}
+
+// retaliationTarget picks the enemy army a settlement strikes back at. Armies assaulting the
+// walls are the first in reach, while besieging armies keep out of reach altogether.
+func (s *World) retaliationTarget(settlement *Settlement) *Army {
+	var target *Army
+
+	for _, army := range s.EnemiesAt(settlement.Name, settlement.Allegiance) {
+		if army.Stance == StanceSiege {
+			continue
+		} else if army.Stance == StanceAssault {
+			return army
+		} else if target == nil {
+			target = army
+		}
+	}
+
+	return target
+}
+
+// stepSieges starves every settlement under siege, costing it HP and people each turn until it
+// falls to the besiegers.
+func (s *World) stepSieges(log *DocumentElement) bool {
+	var (
+		activityObserved = false
+		siegeList        = log.Element(UnorderedList)
+	)
+
+	siegeList.Attributes["style"] = "list-style-type: none;"
+
+	for _, settlement := range SettlementListFromMap(s.Settlements).Sorted() {
+		besiegers := s.Besiegers(settlement)
+		if len(besiegers) == 0 {
+			continue
+		}
+
+		activityObserved = true
+
+		var (
+			hpLoss         = percentOf(settlement.HP.Max, siegeHPPercent)
+			populationLoss = settlement.Population * siegePopulationPercent / 100
+		)
+
+		settlement.HP.Damage(hpLoss)
+		settlement.Population -= populationLoss
+
+		siegeList.Element(ListItem).Text = fmt.Sprintf("Army %s's siege starves %s of %d HP and %d people.",
+			NameLink(besiegers[0].Name), NameLink(settlement.Name), hpLoss, populationLoss)
+
+		if settlement.HP.Current <= 0 {
+			s.captureSettlement(besiegers[0], settlement, siegeList)
+		}
+	}
+
+	return activityObserved
+}
+
+// describeStance describes an army's stance for reports.
+func describeStance(stance Stance) string {
+	if len(stance) == 0 {
+		return "None"
+	}
+
+	return string(stance)
+}
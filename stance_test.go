@@ -0,0 +1,137 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestStanceEngagement(t *testing.T) {
+	tests := []struct {
+		stance     Stance
+		armies     bool
+		settlement bool
+	}{
+		{stance: "", armies: true, settlement: true},
+		{stance: StanceAssault, armies: false, settlement: true},
+		{stance: StanceSiege, armies: false, settlement: false},
+		{stance: StanceHold, armies: false, settlement: false},
+		{stance: StanceSkirmish, armies: true, settlement: false},
+	}
+
+	for _, test := range tests {
+		if !test.stance.Known() {
+			t.Errorf("stance %q is unknown", test.stance)
+		}
+
+		if engages := test.stance.EngagesArmies(); engages != test.armies {
+			t.Errorf("stance %q engages armies: %t, expected %t", test.stance, engages, test.armies)
+		}
+
+		if engages := test.stance.EngagesSettlement(); engages != test.settlement {
+			t.Errorf("stance %q engages settlements: %t, expected %t", test.stance, engages, test.settlement)
+		}
+	}
+
+	if Stance("charge").Known() {
+		t.Errorf("stance charge is known, expected it to be unknown")
+	}
+}
+
+// newTestSiege puts an Aundair army of each given stance in Thrane's Olath.
+func newTestSiege(stances ...Stance) *World {
+	world := newTestWorld()
+	first := *world.Armies["First Host"]
+	delete(world.Armies, "First Host")
+
+	for _, stance := range stances {
+		army := first
+		army.Name = "Army " + describeStance(stance)
+		army.HP = &HealthTracker{Current: first.HP.Current, Max: first.HP.Max}
+		army.Location = "Olath"
+		army.Destination = "Olath"
+		army.Stance = stance
+
+		world.Armies[army.Name] = &army
+	}
+
+	return world
+}
+
+func TestRetaliationTarget(t *testing.T) {
+	tests := []struct {
+		stances  []Stance
+		expected string
+	}{
+		{stances: []Stance{StanceSiege}},
+		{stances: []Stance{StanceSiege, StanceHold}, expected: "Army hold"},
+		{stances: []Stance{"", StanceAssault, StanceSiege}, expected: "Army assault"},
+	}
+
+	for _, test := range tests {
+		world := newTestSiege(test.stances...)
+
+		if target := world.retaliationTarget(world.Settlements["Olath"]); test.expected == "" && target != nil {
+			t.Errorf("Olath strikes back at %s among %v, expected no army in reach", target.Name, test.stances)
+		} else if test.expected != "" && (target == nil || target.Name != test.expected) {
+			t.Errorf("Olath strikes back at %v among %v, expected %s", target, test.stances, test.expected)
+		}
+	}
+}
+
+func TestStepSieges(t *testing.T) {
+	var (
+		world = newTestSiege(StanceSiege)
+		olath = world.Settlements["Olath"]
+	)
+
+	if !world.stepSieges(Element(Division)) {
+		t.Fatalf("siege of Olath was not reported")
+	} else if olath.HP.Current != 95 || olath.Population != 980 {
+		t.Errorf("Olath has %d HP and %d people after a turn under siege, expected 95 and 980", olath.HP.Current, olath.Population)
+	}
+
+	olath.HP.Current = 5
+	world.stepSieges(Element(Division))
+
+	if olath.Allegiance != "Aundair" || !olath.Occupied {
+		t.Errorf("Olath is held by %s after starving, expected it to fall to Aundair", olath.Allegiance)
+	}
+}
+
+func TestHoldingArmiesLeaveWallsAlone(t *testing.T) {
+	world := newTestSiege(StanceHold, StanceSkirmish)
+
+	for turn := 1; turn <= 5; turn++ {
+		if _, err := world.Turn(turn, NewRoller(rand.New(rand.NewSource(int64(turn)))), nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if olath := world.Settlements["Olath"]; olath.HP.Current != olath.HP.Max || olath.Allegiance != "Thrane" {
+		t.Errorf("Olath has %d HP and is held by %s, expected armies holding and skirmishing to leave it alone", olath.HP.Current, olath.Allegiance)
+	}
+}
+
+func TestStanceOrderDropsTarget(t *testing.T) {
+	world := newTestSiege("", StanceHold)
+	world.Armies["Army hold"].Allegiance = "Thrane"
+
+	army := world.Armies["Army None"]
+	army.Target = "Army hold"
+
+	if err := world.applyOrder("Aundair", Order{Army: army.Name, Type: StanceOrder, Target: string(StanceSkirmish)}); err != nil {
+		t.Fatal(err)
+	} else if army.Target != "Army hold" {
+		t.Errorf("skirmishing army dropped its target, expected it to keep chasing it")
+	}
+
+	if err := world.applyOrder("Aundair", Order{Army: army.Name, Type: StanceOrder, Target: string(StanceSiege)}); err != nil {
+		t.Fatal(err)
+	} else if army.Target != "" {
+		t.Errorf("besieging army is still chasing %s, expected it to drop its target", army.Target)
+	}
+
+	if err := world.applyOrder("Aundair", Order{Army: army.Name, Type: StanceOrder, Target: "charge"}); err == nil {
+		t.Errorf("order to take an unknown stance was accepted")
+	}
+}
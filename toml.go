@@ -1,20 +1,25 @@
 package main
 
 import (
+	"bytes"
 	"github.com/BurntSushi/toml"
 	"os"
 )
 
 func LoadWorld(path string) (*World, error) {
 	world := &World{}
-	if _, err := toml.DecodeFile(path, world); err != nil {
+	if err := decodeVersioned(path, worldMigrations, world); err != nil {
 		return nil, err
+	} else if problems := world.Validate(); len(problems) > 0 {
+		return nil, &ValidationError{Path: path, Problems: problems}
 	}
 
 	return world, nil
 }
 
 func WriteWorld(path string, world *World) error {
+	world.SchemaVersion = WorldSchemaVersion
+
 	if file, err := os.OpenFile(path, os.O_RDWR|os.O_TRUNC|os.O_CREATE, 0644); err != nil {
 		return err
 	} else if err := toml.NewEncoder(file).Encode(world); err != nil {
@@ -23,3 +28,19 @@ func WriteWorld(path string, world *World) error {
 
 	return nil
 }
+
+// Clone makes a deep copy of a world by writing it out and reading it back in, so that the copy
+// shares nothing with the original.
+func (s *World) Clone() (*World, error) {
+	buffer := &bytes.Buffer{}
+	if err := toml.NewEncoder(buffer).Encode(s); err != nil {
+		return nil, err
+	}
+
+	clone := &World{}
+	if _, err := toml.Decode(buffer.String(), clone); err != nil {
+		return nil, err
+	}
+
+	return clone, nil
+}
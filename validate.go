@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationError lists every problem found in a world file, each with the key path of the
+// value at fault.
+type ValidationError struct {
+	Path     string
+	Problems []string
+}
+
+func (s *ValidationError) Error() string {
+	var lines []string
+	for _, problem := range s.Problems {
+		lines = append(lines, fmt.Sprintf("%s: %s", s.Path, problem))
+	}
+
+	return fmt.Sprintf("invalid world:\n  %s", strings.Join(lines, "\n  "))
+}
+
+// worldValidator gathers the problems found while checking a world.
+type worldValidator struct {
+	world    *World
+	problems []string
+}
+
+func (s *worldValidator) problem(key, format string, args ...interface{}) {
+	s.problems = append(s.problems, fmt.Sprintf("%s: %s", key, fmt.Sprintf(format, args...)))
+}
+
+func (s *worldValidator) checkRoll(key string, roll RollSpec) {
+	if err := roll.Check(); err != nil {
+		s.problem(key, "%v", err)
+	}
+}
+
+func (s *worldValidator) checkAllegiance(key, allegiance string) {
+	if _, found := s.world.Actors[allegiance]; !found {
+		s.problem(key, "%q is not one of the world's actors", allegiance)
+	}
+}
+
+func (s *worldValidator) checkSettlement(key, name string) {
+	if _, found := s.world.Settlements[name]; !found {
+		s.problem(key, "%q is not a settlement", name)
+	}
+}
+
+func (s *worldValidator) checkHealth(key string, health *HealthTracker) {
+	if health == nil {
+		s.problem(key, "HP is missing")
+	} else if health.Current > health.Max {
+		s.problem(key, "current HP %d is above the maximum of %d", health.Current, health.Max)
+	}
+}
+
+func knownFortificationType(fortificationType FortificationType) bool {
+	_, known := FortificationRules[fortificationType]
+	return known
+}
+
+func sortedKeys(keys []string) []string {
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *worldValidator) checkSettlements() {
+	var names []string
+	for name := range s.world.Settlements {
+		names = append(names, name)
+	}
+
+	for _, name := range sortedKeys(names) {
+		var (
+			settlement = s.world.Settlements[name]
+			key        = fmt.Sprintf("Settlements.%q", name)
+		)
+
+		if settlement.Name != name {
+			s.problem(key+".Name", "%q does not match the settlement's key", settlement.Name)
+		}
+
+		s.checkAllegiance(key+".Allegiance", settlement.Allegiance)
+		s.checkHealth(key+".HP", settlement.HP)
+		s.checkRoll(key+".DamageRoll", settlement.DamageRoll)
+
+		for idx, fortification := range settlement.Fortifications {
+			fortificationKey := fmt.Sprintf("%s.Fortifications[%d]", key, idx)
+
+			if !knownFortificationType(fortification.Type) {
+				s.problem(fortificationKey+".Type", "unknown fortification type %d", fortification.Type)
+			}
+
+			s.checkHealth(fortificationKey+".HP", fortification.HP)
+		}
+
+		for idx, project := range settlement.Construction {
+			if _, found := s.world.Blueprints()[project.Blueprint]; !found {
+				s.problem(fmt.Sprintf("%s.Construction[%d].Blueprint", key, idx), "%q is not a blueprint", project.Blueprint)
+			}
+		}
+
+		for idx, recruit := range settlement.Training {
+			recruitKey := fmt.Sprintf("%s.Training[%d]", key, idx)
+
+			if _, found := s.world.Recruits()[recruit.Archetype]; !found {
+				s.problem(recruitKey+".Archetype", "%q is not an army archetype that may be recruited", recruit.Archetype)
+			}
+
+			if _, found := s.world.Armies[recruit.Name]; found {
+				s.problem(recruitKey+".Name", "%q is already the name of an army", recruit.Name)
+			}
+		}
+	}
+}
+
+func (s *worldValidator) checkArmies() {
+	var names []string
+	for name := range s.world.Armies {
+		names = append(names, name)
+	}
+
+	for _, name := range sortedKeys(names) {
+		var (
+			army = s.world.Armies[name]
+			key  = fmt.Sprintf("Armies.%q", name)
+		)
+
+		if army.Name != name {
+			s.problem(key+".Name", "%q does not match the army's key", army.Name)
+		}
+
+		s.checkAllegiance(key+".Allegiance", army.Allegiance)
+		s.checkHealth(key+".HP", army.HP)
+		s.checkRoll(key+".AttackRoll", army.AttackRoll)
+		s.checkRoll(key+".DamageRoll", army.DamageRoll)
+
+		if army.Morale < 0 || army.Morale > maxMorale {
+			s.problem(key+".Morale", "%d is not between 0 and %d", army.Morale, maxMorale)
+		}
+
+		if !army.Stance.Known() {
+			s.problem(key+".Stance", "unknown stance %q", army.Stance)
+		}
+
+		// Armies on the road have no location until they arrive
+		if army.Transit != nil {
+			s.checkSettlement(key+".Transit.From", army.Transit.From)
+			s.checkSettlement(key+".Transit.To", army.Transit.To)
+		} else {
+			s.checkSettlement(key+".Location", army.Location)
+		}
+
+		s.checkSettlement(key+".Destination", army.Destination)
+
+		for idx, stop := range army.Route {
+			s.checkSettlement(fmt.Sprintf("%s.Route[%d]", key, idx), stop)
+		}
+
+		if _, found := s.world.Armies[army.Target]; len(army.Target) > 0 && !found {
+			s.problem(key+".Target", "%q is not an army", army.Target)
+		}
+	}
+}
+
+func (s *worldValidator) checkActors() {
+	var names []string
+	for name := range s.world.Actors {
+		names = append(names, name)
+	}
+
+	for _, name := range sortedKeys(names) {
+		if actor := s.world.Actors[name]; actor.Name != name {
+			s.problem(fmt.Sprintf("Actors.%q.Name", name), "%q does not match the actor's key", actor.Name)
+		}
+	}
+}
+
+func (s *worldValidator) checkRoutes() {
+	for idx, route := range s.world.Routes {
+		key := fmt.Sprintf("Routes[%d]", idx)
+
+		s.checkSettlement(key+".From", route.From)
+		s.checkSettlement(key+".To", route.To)
+
+		if route.Distance < 0 {
+			s.problem(key+".Distance", "distance %d is negative", route.Distance)
+		}
+	}
+}
+
+func (s *worldValidator) checkRules() {
+	if criticals := s.world.Criticals(); criticals != nil {
+		for idx, fumble := range criticals.FumbleTable {
+			s.checkRoll(fmt.Sprintf("Rules.Criticals.FumbleTable[%d].Damage", idx), fumble.Damage)
+
+			if fumble.Morale < 0 || fumble.Morale > maxMorale {
+				s.problem(fmt.Sprintf("Rules.Criticals.FumbleTable[%d].Morale", idx), "%d is not between 0 and %d", fumble.Morale, maxMorale)
+			}
+		}
+	}
+
+	if s.world.Rules == nil {
+		return
+	}
+
+	if economy := s.world.Rules.Economy; economy != nil && (economy.OccupiedPercent < 0 || economy.OccupiedPercent > 100) {
+		s.problem("Rules.Economy.OccupiedPercent", "%d is not between 0 and 100", economy.OccupiedPercent)
+	}
+
+	var recruitNames []string
+	for name := range s.world.Rules.Recruits {
+		recruitNames = append(recruitNames, name)
+	}
+
+	for _, name := range sortedKeys(recruitNames) {
+		key := fmt.Sprintf("Rules.Recruits.%q", name)
+
+		s.checkRoll(key+".AttackRoll", s.world.Rules.Recruits[name].AttackRoll)
+		s.checkRoll(key+".DamageRoll", s.world.Rules.Recruits[name].DamageRoll)
+	}
+
+	var names []string
+	for name := range s.world.Rules.Blueprints {
+		names = append(names, name)
+	}
+
+	for _, name := range sortedKeys(names) {
+		if blueprint := s.world.Rules.Blueprints[name]; !knownFortificationType(blueprint.Type) {
+			s.problem(fmt.Sprintf("Rules.Blueprints.%q.Type", name), "unknown fortification type %d", blueprint.Type)
+		}
+	}
+}
+
+// Validate checks every reference and roll in the world so that a broken world is caught when
+// it is loaded rather than part way through a turn. Every problem found is reported, not just
+// the first.
+func (s *World) Validate() []string {
+	validator := &worldValidator{
+		world: s,
+	}
+
+	validator.checkActors()
+	validator.checkSettlements()
+	validator.checkArmies()
+	validator.checkRoutes()
+	validator.checkRules()
+
+	return validator.problems
+}
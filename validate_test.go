@@ -0,0 +1,46 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	world := newTestWorld()
+	if problems := world.Validate(); len(problems) > 0 {
+		t.Fatalf("test world has problems:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	army := world.Armies["First Host"]
+	army.Location = "UNSET_LOCATION"
+	army.Allegiance = "Karrnath"
+	army.HP.Current = 80
+	army.DamageRoll = RollSpec{Die("2d")}
+	world.Settlements["Wroat"].Name = "Sharn"
+
+	expected := []string{
+		`Settlements."Wroat".Name: "Sharn" does not match the settlement's key`,
+		`Armies."First Host".Allegiance: "Karrnath" is not one of the world's actors`,
+		`Armies."First Host".HP: current HP 80 is above the maximum of 50`,
+		`Armies."First Host".DamageRoll: `,
+		`Armies."First Host".Location: "UNSET_LOCATION" is not a settlement`,
+	}
+
+	problems := strings.Join(world.Validate(), "\n")
+	for _, problem := range expected {
+		if !strings.Contains(problems, problem) {
+			t.Errorf("problems do not mention %q:\n%s", problem, problems)
+		}
+	}
+}
+
+func TestTurnWithArmyAtUnknownLocation(t *testing.T) {
+	world := newTestWorld()
+	world.Armies["First Host"].Location = "UNSET_LOCATION"
+	world.Armies["First Host"].Destination = "UNSET_LOCATION"
+
+	if _, err := world.Turn(1, NewRoller(rand.New(rand.NewSource(1))), nil, nil); err == nil {
+		t.Errorf("turn with an army at an unknown location succeeded, expected an error")
+	}
+}
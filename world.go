@@ -20,16 +20,21 @@ func Printf(format string, args ...interface{}) {
 }
 
 type World struct {
+	SchemaVersion int
+
 	Settlements map[string]*Settlement
 	Armies      map[string]*Army
 	Actors      map[string]*WorldActor
+	Routes      []Route
+	Rules       *Rules
 }
 
 func NewWorld() *World {
 	return &World{
-		Settlements: make(map[string]*Settlement),
-		Armies:      make(map[string]*Army),
-		Actors:      make(map[string]*WorldActor),
+		SchemaVersion: WorldSchemaVersion,
+		Settlements:   make(map[string]*Settlement),
+		Armies:        make(map[string]*Army),
+		Actors:        make(map[string]*WorldActor),
 	}
 }
 
@@ -79,6 +84,79 @@ func (s *World) ArmiesAt(location string) ArmyList {
 	return armies
 }
 
+// EnemiesAt returns the armies at a location that are still fighting and are not loyal to the
+// given actor.
+func (s *World) EnemiesAt(location, allegiance string) ArmyList {
+	var enemies ArmyList
+	for _, army := range s.ArmiesAt(location) {
+		if !army.Destroyed && army.Allegiance != allegiance {
+			enemies = append(enemies, army)
+		}
+	}
+
+	return enemies.Sorted()
+}
+
+// describeHitChances formats the chance of an attack roll hitting each of the given targets.
+func (s *World) describeHitChances(attack RollSpec, targetNames []string, targetACs []int) string {
+	if len(targetNames) == 0 {
+		return "No targets"
+	}
+
+	var chances []string
+	for idx, name := range targetNames {
+		if chance, estimated, err := s.HitChance(attack, targetACs[idx]); err != nil {
+			chances = append(chances, fmt.Sprintf("%s: %v", name, err))
+		} else if estimated {
+			chances = append(chances, printer.Sprintf("about %.0f%% vs %s (AC %d)", chance*100, name, targetACs[idx]))
+		} else {
+			chances = append(chances, printer.Sprintf("%.0f%% vs %s (AC %d)", chance*100, name, targetACs[idx]))
+		}
+	}
+
+	return strings.Join(chances, ", ")
+}
+
+// ArmyHitChances describes the chance of the army hitting whatever it would attack where it is
+// now: enemy armies first and then an enemy settlement. Armies on the road have nothing in reach.
+func (s *World) ArmyHitChances(army *Army) string {
+	var (
+		names []string
+		acs   []int
+	)
+
+	if army.InTransit() {
+		return s.describeHitChances(army.AttackRoll, names, acs)
+	}
+
+	for _, enemy := range s.EnemiesAt(army.Location, army.Allegiance) {
+		names = append(names, enemy.Name)
+		acs = append(acs, enemy.AC)
+	}
+
+	if settlement, found := s.Settlements[army.Location]; found && len(names) == 0 && settlement.Allegiance != army.Allegiance {
+		names = append(names, settlement.Name)
+		acs = append(acs, settlement.AC())
+	}
+
+	return s.describeHitChances(army.AttackRoll, names, acs)
+}
+
+// SettlementHitChances describes the chance of the settlement hitting each enemy army present.
+func (s *World) SettlementHitChances(settlement *Settlement) string {
+	var (
+		names []string
+		acs   []int
+	)
+
+	for _, enemy := range s.EnemiesAt(settlement.Name, settlement.Allegiance) {
+		names = append(names, enemy.Name)
+		acs = append(acs, enemy.AC)
+	}
+
+	return s.describeHitChances(RollSpec{settlement.AttackRoll()}, names, acs)
+}
+
 func (s *World) ArmiesByActor() map[string]ArmyList {
 	armyMap := make(map[string]ArmyList)
 	for _, army := range s.Armies {
@@ -200,6 +278,17 @@ func (s *World) WriteWorld(body *DocumentElement) {
 			row.Element(TableCell).Element(Span).Text = printer.Sprint(settlement.AC())
 		})
 
+		statsTable.Element(TableRow).Do(func(row *DocumentElement) {
+			statsCell := row.Element(TableCell)
+			statsCell.Attributes["style"] = "padding-right: 30px;"
+
+			fieldName := statsCell.Element(Span)
+			fieldName.Attributes["style"] = "font-weight: bold;"
+			fieldName.Text = "AC Breakdown"
+
+			row.Element(TableCell).Element(Span).Text = settlement.ACBreakdown()
+		})
+
 		statsTable.Element(TableRow).Do(func(row *DocumentElement) {
 			statsCell := row.Element(TableCell)
 			statsCell.Attributes["style"] = "padding-right: 30px;"
@@ -211,6 +300,17 @@ func (s *World) WriteWorld(body *DocumentElement) {
 			row.Element(TableCell).Element(Span).Text = printer.Sprint(settlement.AttackRoll())
 		})
 
+		statsTable.Element(TableRow).Do(func(row *DocumentElement) {
+			statsCell := row.Element(TableCell)
+			statsCell.Attributes["style"] = "padding-right: 30px;"
+
+			fieldName := statsCell.Element(Span)
+			fieldName.Attributes["style"] = "font-weight: bold;"
+			fieldName.Text = "Hit Chance"
+
+			row.Element(TableCell).Element(Span).Text = s.SettlementHitChances(settlement)
+		})
+
 		statsTable.Element(TableRow).Do(func(row *DocumentElement) {
 			statsCell := row.Element(TableCell)
 			statsCell.Attributes["style"] = "padding-right: 30px;"
@@ -222,11 +322,35 @@ func (s *World) WriteWorld(body *DocumentElement) {
 			row.Element(TableCell).Element(Span).Text = printer.Sprint(settlement.DamageRoll)
 		})
 
+		statsTable.Element(TableRow).Do(func(row *DocumentElement) {
+			statsCell := row.Element(TableCell)
+			statsCell.Attributes["style"] = "padding-right: 30px;"
+
+			fieldName := statsCell.Element(Span)
+			fieldName.Attributes["style"] = "font-weight: bold;"
+			fieldName.Text = "Under Construction"
+
+			row.Element(TableCell).Element(Span).Text = settlement.ConstructionDescription()
+		})
+
+		statsTable.Element(TableRow).Do(func(row *DocumentElement) {
+			statsCell := row.Element(TableCell)
+			statsCell.Attributes["style"] = "padding-right: 30px;"
+
+			fieldName := statsCell.Element(Span)
+			fieldName.Attributes["style"] = "font-weight: bold;"
+			fieldName.Text = "In Training"
+
+			row.Element(TableCell).Element(Span).Text = settlement.TrainingDescription()
+		})
+
 		detailsCell = statsRow.Element(TableCell)
 		fortificationList := detailsCell.Element(UnorderedList)
 		fortificationList.Attributes["style"] = "list-style-type: none;"
 
-		for _, fortification := range settlement.Fortifications {
+		for _, effect := range settlement.FortificationEffects() {
+			fortification := effect.Fortification
+
 			listItem := fortificationList.Element(ListItem)
 			listItem.Attributes["style"] = "padding-top: 10px;"
 			listItem.Element(Span).Text = fortification.Name
@@ -234,6 +358,36 @@ func (s *World) WriteWorld(body *DocumentElement) {
 			statsTable = listItem.Element(Table)
 			statsTable.Attributes["style"] = "margin-left: 20px;"
 
+			statsTable.Element(TableRow).Do(func(row *DocumentElement) {
+				fieldName := row.Element(TableCell).Element(Span)
+				fieldName.Attributes["style"] = "font-weight: bold;"
+				fieldName.Text = "Type"
+
+				row.Element(TableCell).Element(Span).Text = fortification.Type.String()
+			})
+
+			if fortification.HP != nil {
+				statsTable.Element(TableRow).Do(func(row *DocumentElement) {
+					fieldName := row.Element(TableCell).Element(Span)
+					fieldName.Attributes["style"] = "font-weight: bold;"
+					fieldName.Text = "HP"
+
+					row.Element(TableCell).Element(Span).Text = printer.Sprintf("%d / %d", fortification.HP.Current, fortification.HP.Max)
+				})
+			}
+
+			statsTable.Element(TableRow).Do(func(row *DocumentElement) {
+				fieldName := row.Element(TableCell).Element(Span)
+				fieldName.Attributes["style"] = "font-weight: bold;"
+				fieldName.Text = "Counts"
+
+				if effect.Counted {
+					row.Element(TableCell).Element(Span).Text = "Yes"
+				} else {
+					row.Element(TableCell).Element(Span).Text = fmt.Sprintf("No, %s", effect.Reason)
+				}
+			})
+
 			statsTable.Element(TableRow).Do(func(row *DocumentElement) {
 				fieldName := row.Element(TableCell).Element(Span)
 				fieldName.Attributes["style"] = "font-weight: bold;"
@@ -257,7 +411,7 @@ func (s *World) WriteWorld(body *DocumentElement) {
 	armyDetailsDiv := rootDiv.Element(Division)
 	armyDetailsDiv.Element(H1).Text = "Army Details"
 
-	for _, army := range s.Armies {
+	for _, army := range ArmyListFromMap(s.Armies).Sorted() {
 		armyDiv := armyDetailsDiv.Element(Division)
 		armyDiv.Element(Span).Attributes["id"] = DocumentID(army.Name)
 		armyDiv.Element(H2).Text = army.Name
@@ -268,7 +422,23 @@ func (s *World) WriteWorld(body *DocumentElement) {
 			fieldName.Attributes["style"] = "font-weight: bold;"
 			fieldName.Text = "Location"
 
-			row.Element(TableCell).Element(Span).Text = printer.Sprint(army.Location)
+			row.Element(TableCell).Element(Span).Text = printer.Sprint(army.LocationDescription())
+		})
+
+		table.Element(TableRow).Do(func(row *DocumentElement) {
+			fieldName := row.Element(TableCell).Element(Span)
+			fieldName.Attributes["style"] = "font-weight: bold;"
+			fieldName.Text = "Destination"
+
+			row.Element(TableCell).Element(Span).Text = army.Destination
+		})
+
+		table.Element(TableRow).Do(func(row *DocumentElement) {
+			fieldName := row.Element(TableCell).Element(Span)
+			fieldName.Attributes["style"] = "font-weight: bold;"
+			fieldName.Text = "Route"
+
+			row.Element(TableCell).Element(Span).Text = army.RemainingRoute()
 		})
 
 		table.Element(TableRow).Do(func(row *DocumentElement) {
@@ -303,6 +473,14 @@ func (s *World) WriteWorld(body *DocumentElement) {
 			row.Element(TableCell).Element(Span).Text = army.AttackRoll.String()
 		})
 
+		table.Element(TableRow).Do(func(row *DocumentElement) {
+			fieldName := row.Element(TableCell).Element(Span)
+			fieldName.Attributes["style"] = "font-weight: bold;"
+			fieldName.Text = "Hit Chance"
+
+			row.Element(TableCell).Element(Span).Text = s.ArmyHitChances(army)
+		})
+
 		table.Element(TableRow).Do(func(row *DocumentElement) {
 			fieldName := row.Element(TableCell).Element(Span)
 			fieldName.Attributes["style"] = "font-weight: bold;"
@@ -310,10 +488,50 @@ func (s *World) WriteWorld(body *DocumentElement) {
 
 			row.Element(TableCell).Element(Span).Text = printer.Sprint(army.DamageRoll)
 		})
+
+		table.Element(TableRow).Do(func(row *DocumentElement) {
+			fieldName := row.Element(TableCell).Element(Span)
+			fieldName.Attributes["style"] = "font-weight: bold;"
+			fieldName.Text = "Morale"
+
+			if army.Routed {
+				row.Element(TableCell).Element(Span).Text = printer.Sprintf("%d / %d (routed)", army.Morale, maxMorale)
+			} else {
+				row.Element(TableCell).Element(Span).Text = printer.Sprintf("%d / %d", army.Morale, maxMorale)
+			}
+		})
+
+		table.Element(TableRow).Do(func(row *DocumentElement) {
+			fieldName := row.Element(TableCell).Element(Span)
+			fieldName.Attributes["style"] = "font-weight: bold;"
+			fieldName.Text = "Stance"
+
+			row.Element(TableCell).Element(Span).Text = describeStance(army.Stance)
+		})
+
+		if army.SiegeEngines {
+			table.Element(TableRow).Do(func(row *DocumentElement) {
+				fieldName := row.Element(TableCell).Element(Span)
+				fieldName.Attributes["style"] = "font-weight: bold;"
+				fieldName.Text = "Siege Engines"
+
+				row.Element(TableCell).Element(Span).Text = "Yes"
+			})
+		}
+
+		if army.Surgeons {
+			table.Element(TableRow).Do(func(row *DocumentElement) {
+				fieldName := row.Element(TableCell).Element(Span)
+				fieldName.Attributes["style"] = "font-weight: bold;"
+				fieldName.Text = "Surgeons"
+
+				row.Element(TableCell).Element(Span).Text = "Yes"
+			})
+		}
 	}
 }
 
-func (s *World) stepArmies(log *DocumentElement) bool {
+func (s *World) stepArmies(roller *Roller, log *DocumentElement) (bool, error) {
 	var (
 		forcesReady      ArmyList
 		activityObserved = false
@@ -324,17 +542,32 @@ func (s *World) stepArmies(log *DocumentElement) bool {
 
 	// Any army that moves may not act in the same turn. This is why we track ready armies in the
 	// forcesReady array
-	for _, army := range s.Armies {
+	for _, army := range ArmyListFromMap(s.Armies).Sorted() {
 		// Skip destroyed armies
 		if army.Destroyed {
 			continue
 		}
 
-		// If the destination of the army is not equal to the location then the army needs to move
-		if army.Destination != army.Location {
-			actionList.Element(ListItem).Text = fmt.Sprintf("Army %s is travelling from %s to %s.", NameLink(army.Name), army.Location, army.Destination)
+		// Armies ordered to attack another army pursue it until it is destroyed
+		if target, found := s.Armies[army.Target]; found {
+			if target.Destroyed {
+				army.Target = ""
+			} else if !target.InTransit() {
+				army.Destination = target.Location
+			}
+		}
 
-			activityObserved = true
+		// If the destination of the army is not equal to the location then the army needs to move. Armies
+		// already on the road must keep marching until they reach the next settlement.
+		if army.InTransit() || army.Destination != army.Location {
+			if moved, err := s.moveArmy(army, actionList); err != nil {
+				return false, err
+			} else if moved {
+				activityObserved = true
+			} else {
+				// Armies that can't find a road stay put and may still fight where they are
+				forcesReady = append(forcesReady, army)
+			}
 		} else {
 			// If no move is required then the army is considered ready for combat
 			forcesReady = append(forcesReady, army)
@@ -343,41 +576,46 @@ func (s *World) stepArmies(log *DocumentElement) bool {
 
 	// Allow armies to attack
 	for _, army := range forcesReady.Sorted() {
+		// Routed armies are in no state to fight, and armies may have routed or fallen earlier
+		// in the turn
+		if army.Routed || army.Destroyed {
+			continue
+		}
+
+		// Armies ordered to attack a specific army engage it if it is here and their stance allows
+		if target, found := s.Armies[army.Target]; found && army.Stance.EngagesArmies() && !target.Destroyed && target.Location == army.Location {
+			activityObserved = true
+
+			if err := s.attackArmy(roller, army, target, actionList); err != nil {
+				return activityObserved, err
+			}
+
+			continue
+		}
+
 		attackedOtherArmy := false
 
-		// Check to see if there are any armies in our location first
-		for actorName, otherArmies := range s.ArmiesByActor() {
-			if army.Allegiance != actorName {
-				// Pick the first army in our location
-				for _, target := range otherArmies {
-					if target.Destroyed || army.Location != target.Location {
-						continue
-					}
+		// Only some stances go looking for a fight with the armies here
+		if army.Stance.EngagesArmies() {
+			// Check to see if there are any armies in our location first
+			armiesByActor := s.ArmiesByActor()
+			for _, actor := range s.SortedActors() {
+				if army.Allegiance != actor.Name {
+					// Pick the first army in our location
+					for _, target := range armiesByActor[actor.Name].Sorted() {
+						if target.Destroyed || army.Location != target.Location {
+							continue
+						}
+
+						activityObserved = true
+						attackedOtherArmy = true
 
-					activityObserved = true
-					attackedOtherArmy = true
-
-					if armyAttackRoll, err := army.AttackRoll.Roll(); err != nil {
-						panic(fmt.Sprintf("Bad roll: %v", err))
-					} else if armyAttackRoll >= target.AC {
-						// If the army beats the settlement's AC value then roll the damage
-						if damage, err := army.DamageRoll.Roll(); err != nil {
-							panic(fmt.Sprintf("Bad roll: %v", err))
-						} else {
-							actionList.Element(ListItem).Text = fmt.Sprintf("Army %s attacks army %s (AC: %d) rolling a %d for attack and %d for damage!",
-								NameLink(army.Name), NameLink(target.Name), target.AC, armyAttackRoll, damage)
-
-							// Apply the damage and see if the settlement is overcome
-							if target.Damage(damage); target.Destroyed {
-								actionList.Element(ListItem).Text = fmt.Sprintf("Army %s has destroyed army %s!", NameLink(army.Name), NameLink(target.Name))
-							}
+						if err := s.attackArmy(roller, army, target, actionList); err != nil {
+							return activityObserved, err
 						}
-					} else {
-						actionList.Element(ListItem).Text = fmt.Sprintf("Army %s misses army %s (AC: %d) rolling a %d for attack.",
-							NameLink(army.Name), NameLink(target.Name), target.AC, armyAttackRoll)
-					}
 
-					break
+						break
+					}
 				}
 			}
 		}
@@ -388,51 +626,221 @@ func (s *World) stepArmies(log *DocumentElement) bool {
 
 		// Look up the settlement at the location
 		if target, found := s.Settlements[army.Location]; !found {
-			panic(fmt.Sprintf("Unable to find location %s that army %s reports being in.", army.Location, army.Name))
+			return activityObserved, fmt.Errorf("army %s reports being in %s, which is not a settlement", army.Name, army.Location)
 		} else if army.Allegiance == target.Allegiance {
 			// If this settlement is one of ours now, let's think about what to do next
-
-		} else {
+			if army.Stance == StanceAssault || army.Stance == StanceSiege {
+				army.Stance = ""
+			}
+		} else if army.Stance.EngagesSettlement() {
 			activityObserved = true
 
-			if armyAttackRoll, err := army.AttackRoll.Roll(); err != nil {
-				panic(fmt.Sprintf("Bad roll: %v", err))
-			} else if armyAttackRoll >= target.AC() {
-				// If the army beats the settlement's AC value then roll the damage
-				if damage, err := army.DamageRoll.Roll(); err != nil {
-					panic(fmt.Sprintf("Bad roll: %v", err))
-				} else {
-					actionList.Element(ListItem).Text = fmt.Sprintf("Army %s attacks settlement %s (AC: %d) rolling a %d for attack and %d for damage!",
-						NameLink(army.Name), NameLink(target.Name), target.AC(), armyAttackRoll, damage)
-
-					// Apply the damage and see if the settlement is overcome
-					target.HP.Damage(damage)
-					if target.HP.Current <= 0 {
-						if target.Occupied {
-							// If the settlement was occupied then we're liberating it
-							actionList.Element(ListItem).Text = fmt.Sprintf("Settlement %s has been liberated by army %s!",
-								NameLink(target.Name), NameLink(army.Name))
-
-							target.Occupied = false
-							target.Allegiance = army.Allegiance
-						} else {
-							// If the settlement wasn't occupied then it is now
-							actionList.Element(ListItem).Text = fmt.Sprintf("Settlement %s has been occupied by army %s!",
-								NameLink(target.Name), NameLink(army.Name))
-
-							target.Occupied = true
-							target.Allegiance = army.Allegiance
-						}
-					}
-				}
-			} else {
-				actionList.Element(ListItem).Text = fmt.Sprintf("Army %s misses settlement %s (AC: %d) rolling a %d for attack.",
-					NameLink(army.Name), NameLink(target.Name), target.AC(), armyAttackRoll)
+			if err := s.attackSettlement(roller, army, target, actionList); err != nil {
+				return activityObserved, err
 			}
 		}
 	}
 
-	return activityObserved
+	return activityObserved, nil
+}
+
+// logAttack writes the combat log entry for an attack along with the rolls behind it.
+func logAttack(actionList *DocumentElement, attackerKind, attacker, targetKind, target string, ac int, result AttackResult, attack, damage *RollRecord) *DocumentElement {
+	entry := actionList.Element(ListItem)
+	natural, _ := attack.Natural()
+
+	switch result {
+	case AttackCriticalHit:
+		entry.Attributes["style"] = "font-weight: bold; color: darkred;"
+		entry.Text = fmt.Sprintf("%s %s lands a critical hit on %s %s (AC: %d) with a natural %d, rolling %d for damage!",
+			attackerKind, NameLink(attacker), targetKind, NameLink(target), ac, natural, damage.Total)
+
+	case AttackHit:
+		entry.Text = fmt.Sprintf("%s %s attacks %s %s (AC: %d) rolling a %d for attack and %d for damage!",
+			attackerKind, NameLink(attacker), targetKind, NameLink(target), ac, attack.Total, damage.Total)
+
+	case AttackFumble:
+		entry.Attributes["style"] = "font-style: italic; color: darkblue;"
+		entry.Text = fmt.Sprintf("%s %s fumbles its attack on %s %s (AC: %d) with a natural %d.",
+			attackerKind, NameLink(attacker), targetKind, NameLink(target), ac, natural)
+
+	default:
+		entry.Text = fmt.Sprintf("%s %s misses %s %s (AC: %d) rolling a %d for attack.",
+			attackerKind, NameLink(attacker), targetKind, NameLink(target), ac, attack.Total)
+	}
+
+	if damage != nil {
+		entry.Push(RollDetails(attack, damage))
+	} else {
+		entry.Push(RollDetails(attack))
+	}
+
+	return entry
+}
+
+func (s *World) attackArmy(roller *Roller, army, target *Army, actionList *DocumentElement) error {
+	attack, result, err := s.rollAttack(roller, army.AttackRoll, fmt.Sprintf("%s attacks %s", army.Name, target.Name), target.AC)
+	if err != nil {
+		return fmt.Errorf("army %s: %v", army.Name, err)
+	}
+
+	switch result {
+	case AttackHit, AttackCriticalHit:
+		// If the army beats the other army's AC value then roll the damage
+		damage, err := s.rollDamage(roller, army.DamageRoll, fmt.Sprintf("%s damages %s", army.Name, target.Name), result)
+		if err != nil {
+			return fmt.Errorf("army %s: %v", army.Name, err)
+		}
+
+		logAttack(actionList, "Army", army.Name, "army", target.Name, target.AC, result, attack, damage)
+
+		// Apply the damage and see if the other army is destroyed or breaks
+		if destroyed, err := s.woundArmy(roller, target, damage.Total, army, actionList); err != nil || !destroyed {
+			return err
+		}
+
+		actionList.Element(ListItem).Text = fmt.Sprintf("Army %s has destroyed army %s!", NameLink(army.Name), NameLink(target.Name))
+		return s.alliesShaken(roller, target, actionList)
+
+	case AttackFumble:
+		entry := logAttack(actionList, "Army", army.Name, "army", target.Name, target.AC, result, attack, nil)
+		return s.armyFumbles(roller, army, entry, actionList)
+	}
+
+	logAttack(actionList, "Army", army.Name, "army", target.Name, target.AC, result, attack, nil)
+	return s.shakeArmy(roller, army, s.Morale().MissLoss, nil, actionList)
+}
+
+func (s *World) attackSettlement(roller *Roller, army *Army, target *Settlement, actionList *DocumentElement) error {
+	ac := target.AC()
+	attack, result, err := s.rollAttack(roller, army.AttackRoll, fmt.Sprintf("%s attacks %s", army.Name, target.Name), ac)
+	if err != nil {
+		return fmt.Errorf("army %s: %v", army.Name, err)
+	}
+
+	switch result {
+	case AttackHit, AttackCriticalHit:
+		// If the army beats the settlement's AC value then roll the damage
+		damage, err := s.rollDamage(roller, army.DamageRoll, fmt.Sprintf("%s damages %s", army.Name, target.Name), result)
+		if err != nil {
+			return fmt.Errorf("army %s: %v", army.Name, err)
+		}
+
+		logAttack(actionList, "Army", army.Name, "settlement", target.Name, ac, result, attack, damage)
+
+		// The settlement's walls soak up their share of the damage first
+		remaining, breached := target.DamageFortifications(damage.Total, army.SiegeEngines)
+		if breached != nil {
+			actionList.Element(ListItem).Text = fmt.Sprintf("Army %s has breached the %s of %s!",
+				NameLink(army.Name), breached.Name, NameLink(target.Name))
+		}
+
+		// Apply the rest of the damage and see if the settlement is overcome
+		if target.HP.Damage(remaining); target.HP.Current <= 0 {
+			s.captureSettlement(army, target, actionList)
+		}
+
+		return nil
+
+	case AttackFumble:
+		entry := logAttack(actionList, "Army", army.Name, "settlement", target.Name, ac, result, attack, nil)
+		return s.armyFumbles(roller, army, entry, actionList)
+	}
+
+	logAttack(actionList, "Army", army.Name, "settlement", target.Name, ac, result, attack, nil)
+	return s.shakeArmy(roller, army, s.Morale().MissLoss, nil, actionList)
+}
+
+// captureSettlement hands a settlement that has been overcome to the army's actor, liberating it
+// if it was occupied and occupying it otherwise.
+func (s *World) captureSettlement(army *Army, target *Settlement, actionList *DocumentElement) {
+	if target.Occupied {
+		// If the settlement was occupied then we're liberating it
+		actionList.Element(ListItem).Text = fmt.Sprintf("Settlement %s has been liberated by army %s!",
+			NameLink(target.Name), NameLink(army.Name))
+
+		target.Occupied = false
+		target.Allegiance = army.Allegiance
+	} else {
+		// If the settlement wasn't occupied then it is now
+		actionList.Element(ListItem).Text = fmt.Sprintf("Settlement %s has been occupied by army %s!",
+			NameLink(target.Name), NameLink(army.Name))
+
+		target.Occupied = true
+		target.Allegiance = army.Allegiance
+	}
+
+	// The works and recruits ordered by the settlement's old actor are abandoned
+	target.Repairing = false
+	target.Construction = nil
+	target.Training = nil
+}
+
+// armyFumbles rolls on the fumble table for an army and applies the result. Fumbling shakes the
+// army's morale whether or not there is a fumble table, and the entry rolled may shake it further.
+func (s *World) armyFumbles(roller *Roller, army *Army, entry, actionList *DocumentElement) error {
+	fumble, damage, rolls, err := s.rollFumble(roller, army.Name)
+	if err != nil {
+		return err
+	} else if fumble == nil {
+		return s.shakeArmy(roller, army, s.Morale().FumbleLoss, nil, actionList)
+	}
+
+	describeFumble(entry, fumble, damage, fumble.Morale)
+	entry.Push(RollDetails(rolls...))
+
+	if destroyed, err := s.woundArmy(roller, army, damage, nil, actionList); err != nil {
+		return err
+	} else if destroyed {
+		actionList.Element(ListItem).Text = fmt.Sprintf("Army %s has destroyed itself!", NameLink(army.Name))
+		return s.alliesShaken(roller, army, actionList)
+	}
+
+	return s.shakeArmy(roller, army, s.Morale().FumbleLoss+fumble.Morale, nil, actionList)
+}
+
+func (s *World) settlementAttack(roller *Roller, settlement *Settlement, army *Army, actionList *DocumentElement) error {
+	attack, result, err := s.rollAttack(roller, RollSpec{settlement.AttackRoll()}, fmt.Sprintf("%s attacks %s", settlement.Name, army.Name), army.AC)
+	if err != nil {
+		return fmt.Errorf("settlement %s: %v", settlement.Name, err)
+	}
+
+	switch result {
+	case AttackHit, AttackCriticalHit:
+		// If the settlement beats the army's AC then roll the damage
+		damage, err := s.rollDamage(roller, settlement.DamageRoll, fmt.Sprintf("%s damages %s", settlement.Name, army.Name), result)
+		if err != nil {
+			return fmt.Errorf("settlement %s: %v", settlement.Name, err)
+		}
+
+		logAttack(actionList, "Settlement", settlement.Name, "army", army.Name, army.AC, result, attack, damage)
+
+		// Apply the damage and see if the army falls apart
+		if destroyed, err := s.woundArmy(roller, army, damage.Total, nil, actionList); err != nil || !destroyed {
+			return err
+		}
+
+		actionList.Element(ListItem).Text = fmt.Sprintf("Settlement %s has destroyed army %s!",
+			NameLink(settlement.Name), NameLink(army.Name))
+		return s.alliesShaken(roller, army, actionList)
+
+	case AttackFumble:
+		entry := logAttack(actionList, "Settlement", settlement.Name, "army", army.Name, army.AC, result, attack, nil)
+
+		if fumble, damage, rolls, err := s.rollFumble(roller, settlement.Name); err != nil {
+			return err
+		} else if fumble != nil {
+			describeFumble(entry, fumble, damage, 0)
+			entry.Push(RollDetails(rolls...))
+
+			settlement.HP.Damage(damage)
+		}
+
+		return nil
+	}
+
+	logAttack(actionList, "Settlement", settlement.Name, "army", army.Name, army.AC, result, attack, nil)
+	return nil
 }
 
 func NameLink(name string) *DocumentElement {
@@ -443,7 +851,7 @@ func NameLink(name string) *DocumentElement {
 	return anchor
 }
 
-func (s *World) stepSettlements(log *DocumentElement) bool {
+func (s *World) stepSettlements(roller *Roller, log *DocumentElement) (bool, error) {
 	var activityObserved = false
 
 	actionList := log.Element(UnorderedList)
@@ -462,38 +870,23 @@ func (s *World) stepSettlements(log *DocumentElement) bool {
 			continue
 		}
 
-		for _, army := range s.Armies {
-			// Settlements can only attack the first army they see currently
-			if !army.Destroyed && army.Location == settlement.Name && army.Allegiance != settlement.Allegiance {
-				activityObserved = true
-
-				if settlementAttackRoll, damage, err := settlement.RollAttack(); err != nil {
-					panic(fmt.Sprintf("Bad roll: %v", err))
-				} else if settlementAttackRoll >= army.AC {
-					// If the settlement beats the army's AC then roll the damage
-					actionList.Element(ListItem).Text = fmt.Sprintf("Settlement %s attacks army %s (AC: %d) rolling a %d for attack and %d for damage!",
-						NameLink(settlement.Name), NameLink(army.Name), army.AC, settlementAttackRoll, damage)
-
-					// Apply the damage and see if the army falls apart
-					army.Damage(damage)
-					if army.Destroyed {
-						actionList.Element(ListItem).Text = fmt.Sprintf("Settlement %s has destroyed army %s!",
-							NameLink(settlement.Name), NameLink(army.Name))
-					}
-				} else {
-					actionList.Element(ListItem).Text = fmt.Sprintf("Settlement %s misses army %s (AC: %d) rolling a %d for attack.",
-						NameLink(settlement.Name), NameLink(army.Name), army.AC, settlementAttackRoll)
-				}
+		// Settlements can only attack one army at a time, and can't reach armies besieging them
+		if army := s.retaliationTarget(settlement); army != nil {
+			activityObserved = true
 
-				break
+			if err := s.settlementAttack(roller, settlement, army, actionList); err != nil {
+				return activityObserved, err
 			}
 		}
 	}
 
-	return activityObserved
+	return activityObserved, nil
 }
 
-func (s *World) Turn(turnID int, output *strings.Builder) bool {
+// Turn runs a single turn of the world, writing the turn report to output unless it is nil. An
+// error is returned if a roll in the world can't be made, leaving the world part way through
+// the turn.
+func (s *World) Turn(turnID int, roller *Roller, orders []*OrderSet, output *strings.Builder) (bool, error) {
 	html := Element("html")
 	body := html.Element(HTBody)
 
@@ -502,16 +895,41 @@ func (s *World) Turn(turnID int, output *strings.Builder) bool {
 	combatLogDiv.Element(H1).Text = "Combat Log"
 	combatLogDiv.Element(H3).Text = fmt.Sprintf("Sim Turn: %d", turnID)
 
+	// Carry out the orders given by each actor before anything else happens
+	s.applyOrders(orders, combatLogDiv)
+
 	// Move armies first
-	armiesActive := s.stepArmies(combatLogDiv)
+	armiesActive, err := s.stepArmies(roller, combatLogDiv)
+	if err != nil {
+		return armiesActive, err
+	}
+
+	// Allow Settlements to act last, then starve those under siege
+	settlementsActive, err := s.stepSettlements(roller, combatLogDiv)
+	if err != nil {
+		return armiesActive || settlementsActive, err
+	}
+
+	siegesActive := s.stepSieges(combatLogDiv)
 
-	// Allow Settlements to act last
-	settlementsActive := s.stepSettlements(combatLogDiv)
+	// Settlements that aren't under attack get on with their repairs, building work and training
+	repairsActive := s.repairFortifications(combatLogDiv)
+	constructionActive := s.stepConstruction(combatLogDiv)
+	trainingActive := s.stepTraining(combatLogDiv)
 
-	// Dump the world state
-	s.WriteWorld(body)
-	html.Output(output)
+	// The wounded recover and routed armies regroup before the armies are paid
+	healingActive := s.stepHealing(combatLogDiv)
+	moraleActive := s.stepMorale(combatLogDiv)
+
+	// Settle each actor's accounts at the end of the turn
+	economyActive := s.stepEconomy(combatLogDiv)
+
+	// Dump the world state, unless nobody is going to read it
+	if output != nil {
+		s.WriteWorld(body)
+		html.Output(output)
+	}
 
 	// Return whether or not any activity took place this turn
-	return armiesActive || settlementsActive
+	return armiesActive || settlementsActive || siegesActive || repairsActive || constructionActive || trainingActive || healingActive || moraleActive || economyActive, nil
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -40,6 +41,8 @@ const (
 	TableHeaders  HTMLTag = "thead"
 	TableRow      HTMLTag = "tr"
 	TableCell     HTMLTag = "td"
+	Details       HTMLTag = "details"
+	Summary       HTMLTag = "summary"
 )
 
 var nonNewlineTags = []HTMLTag{
@@ -51,12 +54,21 @@ type ContentDelegate func(element *DocumentElement)
 type ElementAttributes map[string]string
 
 func (s ElementAttributes) Format() string {
-	output := strings.Builder{}
-	for key, value := range s {
-		output.WriteString(fmt.Sprintf("%s=\"%s\"", key, value))
+	var keys []string
+	for key := range s {
+		keys = append(keys, key)
 	}
 
-	return output.String()
+	// Attributes are written in a stable order so that rendering the same document twice gives
+	// the same output
+	sort.Strings(keys)
+
+	var attributes []string
+	for _, key := range keys {
+		attributes = append(attributes, fmt.Sprintf("%s=\"%s\"", key, s[key]))
+	}
+
+	return strings.Join(attributes, " ")
 }
 
 type DocumentElement struct {